@@ -0,0 +1,12 @@
+// services/user-service/api/api.go
+package api
+
+import _ "embed"
+
+// gRPC のコードの生成（protoc・protoc-gen-go・protoc-gen-go-grpc が必要）
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/user/v1/user.proto
+
+// OpenAPI 3.1 の仕様（手で管理し、契約テストでハンドラーの応答と照合する）
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
@@ -2,98 +2,427 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/api"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/config"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/encryption"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/geo"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/health"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/interceptor"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/lifecycle"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/logging"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/messaging"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/metrics"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/middleware"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/notification"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/outbox"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/ratelimit"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/tracing"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/interface/grpcserver"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/interface/handler"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/usecase"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"gorm.io/gorm"
 )
 
 func main() {
-	// 1. 環境変数の読み込み
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found")
+	// 1. 設定の読み込みと検証（誤りがある場合は起動しない）
+	cfg, err := config.Load(config.Options{DotEnvFiles: []string{".env"}})
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// 有効な設定の表示のサブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	// サービス間通信用のトークンの発行のサブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "issue-service-token" {
+		if err := runIssueServiceToken(cfg.JWT, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to issue service token: %v", err)
+		}
+		return
+	}
+
+	// 構造化ログの設定（標準の log パッケージの出力も同じ形式になる）
+	logger, err := logging.New(os.Stdout, logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	if err != nil {
+		log.Fatalf("Invalid logging configuration: %v", err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("starting user-service",
+		"env", cfg.Env,
+		"port", cfg.Server.Port,
+		"db_driver", cfg.Database.Driver,
+		"event_publisher", cfg.Events.Publisher,
+	)
+
+	// 分散トレースの初期化（エクスポートしない場合も tracecontext は伝播する）
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Environment: cfg.Env,
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
 	}
 
 	// 2. データベース接続の設定
 	dbConfig := database.Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "password"),
-		DBName:   getEnv("DB_NAME", "user_service"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+		Host:     cfg.Database.Host,
+		Port:     strconv.Itoa(cfg.Database.Port),
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		DBName:   cfg.Database.Name,
+		SSLMode:  cfg.Database.SSLMode,
 	}
 
 	// 3. データベース接続
-	db, err := database.NewPostgresDB(dbConfig)
+	var db *gorm.DB
+	if cfg.Database.Driver == "sqlite" {
+		// ローカル実行用（テーブルはAutoMigrateで作成する）
+		db, err = database.NewSQLiteDB(cfg.Database.Path)
+		if err == nil {
+			err = persistence.AutoMigrateModels(db)
+		}
+	} else {
+		db, err = database.NewPostgresDB(dbConfig)
+	}
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
+	}
+	db.Logger = logging.NewGormLogger(logger, logging.GormConfig{
+		SlowThreshold: cfg.Database.SlowQueryThreshold,
+	})
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		fatal("Failed to instrument database", err)
+	}
+
+	// マイグレーションのサブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
+
+	// 起動時のマイグレーション（複数レプリカが同時に実行してもロックで直列化される）
+	if cfg.Database.AutoMigrate && db.Dialector.Name() == "postgres" {
+		if err := runMigrate(db, []string{"up"}); err != nil {
+			fatal("Migration failed", err)
+		}
+	}
+
+	// 個人情報の暗号化（鍵ファイルが未設定の場合は平文で保存する）
+	var encryptor *encryption.FieldEncryptor
+	if path := cfg.PII.KeyFile; path != "" {
+		kms, err := encryption.NewLocalKMS(path)
+		if err != nil {
+			fatal("Failed to load PII key file", err)
+		}
+		encryptor = encryption.NewFieldEncryptor(kms, kms.BlindIndexKey())
+	}
+
+	// 再暗号化のサブコマンド（鍵のローテーション後に実行する）
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt-pii" {
+		if err := runReencryptPII(db, encryptor); err != nil {
+			fatal("Re-encryption failed", err)
+		}
+		return
+	}
+
+	// メトリクスの初期化（コネクションプールの状態も公開する）
+	serviceMetrics := metrics.New()
+	if sqlDB, err := db.DB(); err == nil {
+		if err := serviceMetrics.RegisterDB(cfg.Database.Driver, sqlDB); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
 	}
 
 	// 4. リポジトリの初期化
 	userRepo := persistence.NewUserRepository(db)
+	if encryptor != nil {
+		userRepo = persistence.NewEncryptedUserRepository(db, encryptor)
+	}
+	loginEventRepo := persistence.NewLoginEventRepository(db)
+	loginChallengeRepo := persistence.NewLoginChallengeRepository(db)
+	outboxRepo := persistence.NewOutboxRepository(db)
+	txManager := persistence.NewTxManager(db)
 
 	// 5. JWTサービスの初期化
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
-	jwtExpiration, _ := time.ParseDuration(getEnv("JWT_EXPIRATION", "24h"))
-	jwtService := auth.NewJWTService(jwtSecret, jwtExpiration)
+	jwtService := auth.NewJWTService(auth.Config{
+		SecretKey:        cfg.JWT.Secret,
+		Expires:          cfg.JWT.Expiration,
+		Issuer:           cfg.JWT.Issuer,
+		Audience:         cfg.JWT.Audience,
+		AcceptedAudience: cfg.JWT.AcceptedAudience,
+		DefaultScopes:    cfg.JWT.DefaultScopes,
+		Observer:         serviceMetrics,
+	})
+
+	// ログイン監視の初期化
+	locator := geo.NewNoopLocator()
+	if path := cfg.Login.GeoIPDBPath; path != "" {
+		locator, err = geo.NewFileLocator(path)
+		if err != nil {
+			fatal("Failed to load geo database", err)
+		}
+	}
+	loginMonitor := usecase.NewLoginMonitor(loginEventRepo, loginChallengeRepo, locator, notification.NewLogMailer(), usecase.LoginMonitorConfig{
+		MaxTravelSpeedKmh:   cfg.Login.MaxTravelSpeedKmh,
+		RequireMFAOnAnomaly: cfg.Login.RequireMFAOnAnomaly,
+	})
 
 	// 6. ユースケースの初期化
-	userUseCase := usecase.NewUserUseCase(userRepo, jwtService)
+	userUseCase := usecase.NewUserUseCase(userRepo, outboxRepo, txManager, jwtService, loginMonitor)
+	userUseCase.SetMetrics(serviceMetrics)
+
+	// 起動・停止の管理（停止はHTTP・gRPCサーバー → リレー → ブローカー → データベースの順）
+	lifecycleManager := lifecycle.NewManager(lifecycle.Config{
+		GracePeriod: cfg.Server.ShutdownGracePeriod,
+		DrainDelay:  cfg.Server.ShutdownDrainDelay,
+	})
+
+	// アウトボックスのリレーの起動
+	publisher, err := newEventPublisher(cfg.Events)
+	if err != nil {
+		fatal("Failed to initialize event publisher", err)
+	}
+	relay := outbox.NewRelay(outboxRepo, txManager, publisher, outbox.Config{
+		Source:         cfg.Events.Source,
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  5 * time.Minute,
+	})
+	lifecycleManager.Go("outbox relay", relay.Run)
+	if closer, ok := publisher.(io.Closer); ok {
+		lifecycleManager.Add("event publisher", func(context.Context) error {
+			return closer.Close()
+		})
+	}
+	// レート制限の保存先（redis の場合はレプリカ間で共有する）
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	useRedis := cfg.RateLimit.Enabled && cfg.RateLimit.Backend == "redis"
+	var redisClient *redis.Client
+	if useRedis || cfg.Health.CheckRedis {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr(),
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		lifecycleManager.Add("redis", func(context.Context) error {
+			return redisClient.Close()
+		})
+	}
+	if useRedis {
+		rateLimitStore = ratelimit.NewRedisStore(redisClient)
+	}
+	lifecycleManager.Add("database", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	// 停止処理で作成されたスパンも送信するため最後に停止する
+	lifecycleManager.Add("tracing", shutdownTracing)
 
 	// 7. ハンドラーの初期化
-	userHandler := handler.NewUserHandler(userUseCase)
+	cursorCodec := query.NewCursorCodec([]byte(cfg.CursorSecret()))
+	userHandler := handler.NewUserHandler(userUseCase, cursorCodec)
+
+	// 準備完了の確認に含める依存先
+	healthRegistry := health.NewRegistry(cfg.Health.CheckTimeout)
+	healthRegistry.Register("database", health.DatabaseChecker(db), 0)
+	if pinger, ok := publisher.(health.Pinger); ok {
+		healthRegistry.Register("broker", health.PingChecker(pinger), 0)
+	}
+	if redisClient != nil {
+		// レート制限と同じクライアントで確認する
+		healthRegistry.Register("redis", health.RedisChecker(redisClient), 0)
+	}
+	healthHandler := handler.NewHealthHandler(healthRegistry)
+	lifecycleManager.OnDrain(healthRegistry.SetDraining)
 
 	// 8. Ginルーターの設定
-	router := gin.Default()
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	// 信頼するプロキシ以外からの X-Forwarded-For は無視する（IPアドレスごとの制限の回避を防ぐ）
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
 
 	// 9. 認証ミドルウェアの初期化
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
 	// 10. 基本ミドルウェアの設定
-	router.Use(gin.Recovery())
-	router.Use(gin.Logger())
+	router.Use(gin.CustomRecovery(apierror.Recovered))
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Locale(cfg.I18n.DefaultLocale))
+	router.Use(serviceMetrics.Middleware())
+	router.Use(middleware.AccessLog(logger))
 
 	// ルーティングの設定
-	v1 := router.Group("/api/v1")
-	{
-		users := v1.Group("/users")
-		{
-			// 認証不要のエンドポイント
-			users.POST("/register", userHandler.CreateUser)
-			users.POST("/login", userHandler.Login)
-
-			// 認証が必要なエンドポイント
-			auth := users.Use(authMiddleware.AuthRequired())
-			{
-				auth.GET("/profile", userHandler.GetProfile)
-				auth.PUT("/profile", userHandler.UpdateProfile)
-				auth.POST("/refresh-token", authMiddleware.RefreshToken())
-			}
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(apierror.NotFound)
+	router.NoMethod(apierror.MethodNotAllowed)
+	handler.RegisterHealthRoutes(router, healthHandler)
+	router.GET("/metrics", gin.WrapH(serviceMetrics.Handler()))
+	if cfg.Server.DocsEnabled {
+		docsUI := handler.DocsUIConfig{
+			AssetsURL:    cfg.Server.DocsSwaggerUIURL,
+			CSSIntegrity: cfg.Server.DocsSwaggerUICSSIntegrity,
+			JSIntegrity:  cfg.Server.DocsSwaggerUIJSIntegrity,
 		}
+		if !docsUI.Enabled() {
+			slog.Warn("API docs UI is disabled: set API_DOCS_SWAGGER_UI_CSS_SRI and API_DOCS_SWAGGER_UI_JS_SRI to serve /docs")
+		}
+		docsHandler, err := handler.NewDocsHandler(api.OpenAPI, docsUI)
+		if err != nil {
+			fatal("Failed to load OpenAPI spec", err)
+		}
+		handler.RegisterDocsRoutes(router, docsHandler)
+	}
+	routerConfig := handler.RouterConfig{
+		StepUpMaxAge: cfg.JWT.StepUpMaxAge,
+	}
+	if cfg.RateLimit.Enabled {
+		routerConfig.AuthRateLimit = middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Name:  "auth",
+			Limit: ratelimit.Limit{Requests: cfg.RateLimit.AuthRequests, Window: cfg.RateLimit.AuthWindow},
+			Key:   middleware.KeyByUserID,
+		})
+		routerConfig.UserRateLimit = middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Name:  "default",
+			Limit: ratelimit.Limit{Requests: cfg.RateLimit.DefaultRequests, Window: cfg.RateLimit.DefaultWindow},
+			Key:   middleware.KeyByUserID,
+		})
+	}
+	handler.RegisterRoutes(router, userHandler, authMiddleware, routerConfig)
+
+	// 11. gRPCサーバーの設定（HTTPサーバーと同時に起動・停止する）
+	if cfg.GRPC.Enabled {
+		// HTTP のミドルウェアと同じ保護（リカバリーをアクセスログの内側に置き、パニックも記録する）
+		authInterceptor := interceptor.NewAuthInterceptor(jwtService, grpcserver.MethodPolicies())
+		grpcOptions := append(tracing.GRPCServerOptions(),
+			grpc.ChainUnaryInterceptor(
+				interceptor.UnaryRequestID(),
+				serviceMetrics.UnaryServerInterceptor(),
+				interceptor.UnaryAccessLog(logger),
+				interceptor.UnaryRecovery(),
+				authInterceptor.Unary(),
+			),
+			grpc.ChainStreamInterceptor(
+				interceptor.StreamRequestID(),
+				serviceMetrics.StreamServerInterceptor(),
+				interceptor.StreamAccessLog(logger),
+				interceptor.StreamRecovery(),
+				authInterceptor.Stream(),
+			),
+		)
+		grpcServer := grpc.NewServer(grpcOptions...)
+		grpcserver.Register(grpcServer, grpcserver.NewUserServer(userUseCase, jwtService))
+
+		// 標準のヘルスチェック（停止の開始時に NOT_SERVING にする）
+		grpcHealth := grpchealth.NewServer()
+		healthpb.RegisterHealthServer(grpcServer, grpcHealth)
+		lifecycleManager.OnDrain(grpcHealth.Shutdown)
+		if cfg.GRPC.Reflection {
+			reflection.Register(grpcServer)
+		}
+
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GRPC.Port))
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		lifecycleManager.AddServer("grpc server", listener, grpcServer.Serve, grpcserver.Shutdown(grpcServer))
+		slog.Info("listening for grpc", "addr", listener.Addr().String())
+	}
+
+	// 12. サーバーの起動（SIGINT / SIGTERM で停止処理を行う）
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// 10. サーバーの起動
-	port := getEnv("PORT", "8080")
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	slog.Info("listening", "addr", server.Addr)
+	if err := lifecycleManager.Run(ctx, server); err != nil {
+		fatal("Server stopped with errors", err)
 	}
 }
 
-// 環境変数を取得する関数
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// トレースの対象とするリクエスト（ヘルスチェックとメトリクスの収集は除く）
+func tracedRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, "/health/") && r.URL.Path != "/metrics"
+}
+
+// エラーを出力して終了する関数
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// イベントの送信先を設定から選択する関数
+func newEventPublisher(cfg config.EventsConfig) (messaging.Publisher, error) {
+	switch cfg.Publisher {
+	case "nats":
+		return messaging.NewNATSBroker(context.Background(), messaging.NATSConfig{
+			URL:           cfg.NATSURL,
+			Stream:        cfg.NATSStream,
+			SubjectPrefix: cfg.NATSSubjectPrefix,
+			Durable:       "user-service",
+		})
+	case "inprocess":
+		return messaging.NewInProcessBroker(), nil
+	case "file":
+		return messaging.NewFilePublisher(cfg.FilePath)
+	case "memory":
+		return messaging.NewMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown EVENT_PUBLISHER: %s", cfg.Publisher)
 	}
-	return defaultValue
 }
 
 // 認証ミドルウェア
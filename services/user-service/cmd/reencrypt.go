@@ -0,0 +1,26 @@
+// services/user-service/cmd/reencrypt.go
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/encryption"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"gorm.io/gorm"
+)
+
+// 1回のクエリで処理する行数
+const reencryptBatchSize = 500
+
+// reencrypt-pii サブコマンド
+// 現在の鍵で暗号化されていない行（古い鍵・平文）を暗号化し直す
+func runReencryptPII(db *gorm.DB, encryptor *encryption.FieldEncryptor) error {
+	if encryptor == nil {
+		return fmt.Errorf("PII_KEY_FILE is not set")
+	}
+
+	result, err := persistence.NewUserReencryptor(db, encryptor, reencryptBatchSize).Run(context.Background())
+	fmt.Printf("scanned %d rows, re-encrypted %d, skipped %d modified concurrently\n", result.Scanned, result.Updated, result.Skipped)
+	return err
+}
@@ -0,0 +1,55 @@
+// services/user-service/cmd/service_token.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/config"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+)
+
+// サービス間通信用のトークンに付与できるスコープ
+var serviceTokenScopes = []string{auth.ScopeUsersRead, auth.ScopeProfileRead}
+
+// issue-service-token サブコマンド（issue-service-token [-ttl 1h] <service-name> [scope...]）
+// 他のサービスが gRPC の GetUser などを呼び出すためのトークンを標準出力に書き出す
+// スコープを省略した場合は users:read を付与する
+func runIssueServiceToken(cfg config.JWTConfig, args []string) error {
+	flags := flag.NewFlagSet("issue-service-token", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", time.Hour, "token lifetime")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: issue-service-token [-ttl 1h] <service-name> [scope...]")
+	}
+	if *ttl <= 0 {
+		return fmt.Errorf("ttl must be positive: %s", *ttl)
+	}
+
+	name, scopes := flags.Arg(0), flags.Args()[1:]
+	if len(scopes) == 0 {
+		scopes = []string{auth.ScopeUsersRead}
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(serviceTokenScopes, scope) {
+			return fmt.Errorf("scope %q cannot be granted to a service (allowed: %v)", scope, serviceTokenScopes)
+		}
+	}
+
+	jwtService := auth.NewJWTService(auth.Config{
+		SecretKey: cfg.Secret,
+		Expires:   cfg.Expiration,
+		Issuer:    cfg.Issuer,
+		Audience:  cfg.Audience,
+	})
+	token, err := jwtService.IssueServiceToken(name, scopes, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
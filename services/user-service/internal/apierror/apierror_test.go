@@ -0,0 +1,163 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "wrapped domain error", err: fmt.Errorf("create user: %w", domain.ErrEmailAlreadyExists), wantStatus: http.StatusConflict, wantCode: CodeEmailAlreadyExists},
+		{name: "invalid credentials", err: domain.ErrInvalidCredentials, wantStatus: http.StatusUnauthorized, wantCode: CodeInvalidCredentials},
+		{name: "not found", err: domain.ErrUserNotFound, wantStatus: http.StatusNotFound, wantCode: CodeUserNotFound},
+		{name: "query error", err: fmt.Errorf("%w: cannot sort by %q", query.ErrInvalidSort, "password"), wantStatus: http.StatusBadRequest, wantCode: CodeInvalidQuery},
+		{name: "problem", err: New(http.StatusTeapot, "teapot", "short and stout"), wantStatus: http.StatusTeapot, wantCode: "teapot"},
+		{name: "unknown", err: fmt.Errorf("connection reset"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := FromError(tt.err)
+			if p.Status != tt.wantStatus || p.Code != tt.wantCode {
+				t.Errorf("FromError() = %d %s, want %d %s", p.Status, p.Code, tt.wantStatus, tt.wantCode)
+			}
+			if p.Type != "/problems/"+tt.wantCode {
+				t.Errorf("Type = %q", p.Type)
+			}
+		})
+	}
+
+	// 想定外のエラーの内容は返さない
+	if p := FromError(fmt.Errorf("dial tcp 10.0.0.1:5432: connection refused")); strings.Contains(p.Detail, "10.0.0.1") {
+		t.Errorf("internal error detail leaked: %q", p.Detail)
+	}
+}
+
+type signupRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+	Age      int    `json:"age"`
+}
+
+// リクエストを読み込み、失敗した場合の Problem を返す
+func bind(t *testing.T, body string) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("requestID", "req-1")
+	})
+	router.POST("/signup", func(c *gin.Context) {
+		var req signupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondBindError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var p Problem
+	if rec.Code != http.StatusNoContent {
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("invalid problem body %s: %v", rec.Body, err)
+		}
+	}
+	return rec, p
+}
+
+func TestRespondBindError(t *testing.T) {
+	t.Run("field errors", func(t *testing.T) {
+		rec, p := bind(t, `{"email":"not-an-email","password":"short"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, ContentType) {
+			t.Errorf("Content-Type = %q, want %s", got, ContentType)
+		}
+		if p.Code != CodeValidationFailed || p.Instance != "/signup" || p.RequestID != "req-1" || p.Status != http.StatusBadRequest {
+			t.Errorf("problem = %+v", p)
+		}
+
+		want := []FieldError{
+			{Field: "email", Code: "email", Message: "must be a valid email address"},
+			{Field: "password", Code: "min", Message: "must be at least 8 characters"},
+		}
+		if len(p.Errors) != len(want) {
+			t.Fatalf("errors = %+v, want %+v", p.Errors, want)
+		}
+		for i := range want {
+			got := p.Errors[i]
+			if got.Field != want[i].Field || got.Code != want[i].Code || got.Message != want[i].Message {
+				t.Errorf("errors[%d] = %+v, want %+v", i, p.Errors[i], want[i])
+			}
+		}
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		_, p := bind(t, `{"password":"long-enough"}`)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "email" || p.Errors[0].Message != "is required" {
+			t.Errorf("errors = %+v", p.Errors)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, p := bind(t, `{"email":"taro@example.com","password":"long-enough","age":"twenty"}`)
+		if p.Code != CodeValidationFailed || len(p.Errors) != 1 || p.Errors[0].Field != "age" || p.Errors[0].Code != "type" {
+			t.Errorf("problem = %+v", p)
+		}
+	})
+
+	t.Run("malformed json", func(t *testing.T) {
+		_, p := bind(t, `{"email":`)
+		if p.Code != CodeInvalidRequest {
+			t.Errorf("code = %q, want %q", p.Code, CodeInvalidRequest)
+		}
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		_, p := bind(t, ``)
+		if p.Code != CodeInvalidRequest {
+			t.Errorf("code = %q, want %q", p.Code, CodeInvalidRequest)
+		}
+	})
+}
+
+func TestRecovered(t *testing.T) {
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(nil, Recovered))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.Code != CodeInternal {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
@@ -0,0 +1,101 @@
+// services/user-service/internal/apierror/codes.go
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+)
+
+// エラーコード（API の互換性の一部のため、変更しない）
+const (
+	// リクエストの形式
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidQuery     = "invalid_query"
+
+	// 認証・認可
+	CodeUnauthorized             = "unauthorized"
+	CodeInvalidToken             = "invalid_token"
+	CodeInsufficientScope        = "insufficient_scope"
+	CodeReauthenticationRequired = "reauthentication_required"
+	CodeInvalidCredentials       = "invalid_credentials"
+	CodeMFARequired              = "mfa_required"
+	CodeInvalidVerificationCode  = "invalid_verification_code"
+	CodeRateLimited              = "rate_limited"
+
+	// ユーザー
+	CodeUserNotFound       = "user_not_found"
+	CodeEmailAlreadyExists = "email_already_exists"
+	CodeInvalidEmail       = "invalid_email"
+	CodeWeakPassword       = "weak_password"
+
+	// 競合・制約
+	CodeConcurrentModification = "concurrent_modification"
+	CodePreconditionFailed     = "precondition_failed"
+	CodeConflict               = "conflict"
+	CodeReferenceViolation     = "reference_violation"
+	CodeConstraintViolation    = "constraint_violation"
+	CodeSerializationFailure   = "serialization_failure"
+
+	// ルーティング・その他
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+// ドメインエラーとレスポンスの対応
+type mapping struct {
+	err    error
+	status int
+	code   string
+	detail string
+}
+
+// 上から順に errors.Is で照合する
+var mappings = []mapping{
+	{domain.ErrEmailAlreadyExists, http.StatusConflict, CodeEmailAlreadyExists, "Email already exists"},
+	{domain.ErrInvalidEmail, http.StatusBadRequest, CodeInvalidEmail, "Invalid email format"},
+	{domain.ErrWeakPassword, http.StatusBadRequest, CodeWeakPassword, "Password does not meet security requirements"},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password"},
+	{domain.ErrMFARequired, http.StatusForbidden, CodeMFARequired, "Additional verification required"},
+	{domain.ErrInvalidVerificationCode, http.StatusUnauthorized, CodeInvalidVerificationCode, "Invalid or expired verification code"},
+	{domain.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound, "User not found"},
+	{domain.ErrConcurrentModification, http.StatusConflict, CodeConcurrentModification, "Resource has been modified by another request"},
+	{domain.ErrConflict, http.StatusConflict, CodeConflict, "Resource conflict"},
+	{domain.ErrReferenceViolation, http.StatusConflict, CodeReferenceViolation, "Referenced resource does not exist"},
+	{domain.ErrConstraintViolation, http.StatusUnprocessableEntity, CodeConstraintViolation, "Data violates a constraint"},
+	{domain.ErrSerializationFailure, http.StatusConflict, CodeSerializationFailure, "Request conflicted with another request, please retry"},
+}
+
+// クエリ文字列のエラー（メッセージに原因を含むためそのまま返す）
+var queryErrors = []error{
+	query.ErrInvalidLimit,
+	query.ErrInvalidSort,
+	query.ErrInvalidFilter,
+	query.ErrInvalidCursor,
+}
+
+// エラーを Problem に変換する（対応がない場合は 500）
+func FromError(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return New(m.status, m.code, m.detail)
+		}
+	}
+	for _, target := range queryErrors {
+		if errors.Is(err, target) {
+			return New(http.StatusBadRequest, CodeInvalidQuery, err.Error()).
+				WithMessage(CodeInvalidQuery, i18n.Params{"reason": err.Error()})
+		}
+	}
+	return New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
@@ -0,0 +1,130 @@
+// services/user-service/internal/apierror/problem.go
+package apierror
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// problem+json のメディアタイプ（RFC 7807）
+const ContentType = "application/problem+json"
+
+// type の接頭辞（コードごとの説明ページを想定した相対URI）
+const typePrefix = "/problems/"
+
+// 入力項目ごとのエラー
+type FieldError struct {
+	// JSON上のフィールド名
+	Field string `json:"field"`
+	// バリデーションの種類（required / email / min など）
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// 翻訳するメッセージ（カタログの validation.<messageID>）
+	messageID string
+	params    i18n.Params
+}
+
+// エラーレスポンス（RFC 7807 の Problem Details）
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// 発生したリクエストのパス
+	Instance string `json:"instance,omitempty"`
+
+	// 以下は拡張メンバー
+	// クライアントが分岐に使う安定したエラーコード
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// 翻訳するメッセージ（カタログの error.<messageID>。ない場合は Detail のまま返す）
+	messageID string
+	params    i18n.Params
+}
+
+// Problem の作成（title はステータスの説明文）
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+
+		messageID: code,
+	}
+}
+
+// エラーコードとは別のメッセージで返す（同じコードで状況に応じた説明をする場合）
+func (p *Problem) WithMessage(id string, params i18n.Params) *Problem {
+	problem := *p
+	problem.messageID = id
+	problem.params = params
+	return &problem
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
+// problem+json を返して以降のハンドラーを中断する
+// detail と入力項目ごとのメッセージはリクエストの言語に翻訳する
+func Write(c *gin.Context, p *Problem) {
+	problem := p.localize(i18n.LocaleFromContext(c.Request.Context()))
+	problem.Instance = c.Request.URL.Path
+	problem.RequestID = c.GetString("requestID")
+
+	// gin は Content-Type が設定済みの場合は上書きしない
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// 指定した言語に翻訳した Problem
+func (p *Problem) localize(locale string) Problem {
+	problem := *p
+	if message, ok := i18n.Lookup(locale, "error."+p.messageID, p.params); ok {
+		problem.Detail = message
+	}
+	if len(p.Errors) > 0 {
+		problem.Errors = make([]FieldError, len(p.Errors))
+		for i, fe := range p.Errors {
+			if message, ok := i18n.Lookup(locale, "validation."+fe.messageID, fe.params); ok {
+				fe.Message = message
+			}
+			problem.Errors[i] = fe
+		}
+	}
+	return problem
+}
+
+// エラーを対応する Problem に変換して返す
+// 想定外のエラーは詳細を隠して 500 を返し、ログに残す
+func Respond(c *gin.Context, err error) {
+	p := FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(c.Request.Context(), "request failed", "path", c.FullPath(), "error", err)
+	}
+	Write(c, p)
+}
+
+// 存在しないルート（gin.Engine.NoRoute 用）
+func NotFound(c *gin.Context) {
+	Write(c, New(http.StatusNotFound, CodeNotFound, "Resource not found"))
+}
+
+// 対応していないメソッド（gin.Engine.NoMethod 用）
+func MethodNotAllowed(c *gin.Context) {
+	Write(c, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed"))
+}
+
+// パニックからの復帰時のレスポンス（gin.CustomRecovery 用）
+func Recovered(c *gin.Context, recovered any) {
+	Write(c, New(http.StatusInternalServerError, CodeInternal, "Internal server error"))
+}
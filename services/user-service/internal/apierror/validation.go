@@ -0,0 +1,97 @@
+// services/user-service/internal/apierror/validation.go
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// バリデーションエラーのフィールド名を構造体のフィールド名ではなくJSONの名前にする
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// リクエストの読み込み（ShouldBindJSON など）のエラーを Problem に変換する
+func FromBindError(err error) *Problem {
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		p := New(http.StatusBadRequest, CodeValidationFailed, "Request validation failed")
+		for _, fe := range validationErrs {
+			messageID, params := validationMessage(fe)
+			p.Errors = append(p.Errors, newFieldError(fe.Field(), fe.Tag(), messageID, params))
+		}
+		return p
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		p := New(http.StatusBadRequest, CodeValidationFailed, "Request validation failed")
+		p.Errors = []FieldError{
+			newFieldError(typeErr.Field, "type", "type", i18n.Params{"param": typeErr.Type.Kind().String()}),
+		}
+		return p
+	// 本文がオブジェクトでない場合も含む
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return New(http.StatusBadRequest, CodeInvalidRequest, "Request body must be a valid JSON object")
+	default:
+		return New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request format").
+			WithMessage("invalid_request_format", nil)
+	}
+}
+
+// リクエストの読み込みのエラーを返す
+func RespondBindError(c *gin.Context, err error) {
+	Write(c, FromBindError(err))
+}
+
+// 入力項目のエラー（メッセージは Write で翻訳する）
+func newFieldError(field, code, messageID string, params i18n.Params) FieldError {
+	return FieldError{
+		Field:     field,
+		Code:      code,
+		Message:   i18n.T(i18n.English, "validation."+messageID, params),
+		messageID: messageID,
+		params:    params,
+	}
+}
+
+// バリデーションの種類ごとのメッセージ
+func validationMessage(fe validator.FieldError) (string, i18n.Params) {
+	params := i18n.Params{"param": fe.Param()}
+	switch fe.Tag() {
+	case "required", "email":
+		return fe.Tag(), nil
+	case "min", "max":
+		if fe.Kind() == reflect.String {
+			return fe.Tag() + "_length", params
+		}
+		return fe.Tag(), params
+	case "oneof":
+		return "oneof", i18n.Params{"param": strings.Join(strings.Fields(fe.Param()), ", ")}
+	default:
+		return "invalid", nil
+	}
+}
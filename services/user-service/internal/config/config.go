@@ -0,0 +1,193 @@
+package config
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// 実行環境
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// サービス全体の設定
+//
+// 各項目は env タグの環境変数で上書きできる。優先順位は
+// 環境変数 > .env ファイル > YAMLファイル（CONFIG_FILE） > default タグの既定値。
+// secret タグの付いた項目は "<環境変数>_FILE" でファイルから読み込むこともでき
+// （Docker / Kubernetes のシークレット向け）、設定の表示時には伏せ字になる。
+type Config struct {
+	Env        string           `yaml:"env" env:"ENV" default:"development"`
+	Server     ServerConfig     `yaml:"server"`
+	GRPC       GRPCConfig       `yaml:"grpc"`
+	Database   DatabaseConfig   `yaml:"database"`
+	JWT        JWTConfig        `yaml:"jwt"`
+	PII        PIIConfig        `yaml:"pii"`
+	Login      LoginConfig      `yaml:"login"`
+	Events     EventsConfig     `yaml:"events"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Health     HealthConfig     `yaml:"health"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	I18n       I18nConfig       `yaml:"i18n"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Pagination PaginationConfig `yaml:"pagination"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port" env:"PORT" default:"8080"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"15s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"15s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"60s"`
+	// SIGTERM を受けてから処理中のリクエストと周辺の部品の停止を待つ最大時間
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD" default:"30s"`
+	// 準備完了を取り下げてから新しい接続の受付を止めるまでの待ち時間
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+	// X-Forwarded-For を信頼するプロキシ（CIDR。空の場合は接続元のアドレスをそのまま使う）
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+	// /openapi.yaml・/openapi.json・/docs を公開するか（production では既定で公開しない）
+	DocsEnabled bool `yaml:"docs_enabled" env:"API_DOCS_ENABLED" default:"true"`
+	// /docs で読み込む swagger-ui-dist の配置先
+	DocsSwaggerUIURL string `yaml:"docs_swagger_ui_url" env:"API_DOCS_SWAGGER_UI_URL" default:"https://unpkg.com/swagger-ui-dist@5.17.14"`
+	// swagger-ui.css・swagger-ui-bundle.js の Subresource Integrity（両方を設定しない場合は /docs を公開しない）
+	DocsSwaggerUICSSIntegrity string `yaml:"docs_swagger_ui_css_integrity" env:"API_DOCS_SWAGGER_UI_CSS_SRI"`
+	DocsSwaggerUIJSIntegrity  string `yaml:"docs_swagger_ui_js_integrity" env:"API_DOCS_SWAGGER_UI_JS_SRI"`
+}
+
+// サービス間通信用の gRPC サーバー（HTTPサーバーと同じ停止処理で止める）
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" env:"GRPC_ENABLED" default:"false"`
+	Port    int  `yaml:"port" env:"GRPC_PORT" default:"50051"`
+	// サーバーリフレクション（grpcurl などでサービスの定義を取得できる）
+	Reflection bool `yaml:"reflection" env:"GRPC_REFLECTION" default:"false"`
+}
+
+type DatabaseConfig struct {
+	// postgres / sqlite（ローカル実行用）
+	Driver   string `yaml:"driver" env:"DB_DRIVER" default:"postgres"`
+	Path     string `yaml:"path" env:"DB_PATH" default:"user_service.db"`
+	Host     string `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port     int    `yaml:"port" env:"DB_PORT" default:"5432"`
+	User     string `yaml:"user" env:"DB_USER" default:"postgres"`
+	Password string `yaml:"password" env:"DB_PASSWORD" default:"password" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME" default:"user_service"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	// 起動時に未適用のマイグレーションを適用する
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE" default:"false"`
+	// これより遅いクエリを警告としてログに出力する
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
+}
+
+type JWTConfig struct {
+	Secret     string        `yaml:"secret" env:"JWT_SECRET" default:"your-secret-key" secret:"true"`
+	Expiration time.Duration `yaml:"expiration" env:"JWT_EXPIRATION" default:"24h"`
+	Issuer     string        `yaml:"issuer" env:"JWT_ISSUER" default:"user-service"`
+	// 発行するトークンの対象サービス
+	Audience []string `yaml:"audience" env:"JWT_AUDIENCE" default:"user-service"`
+	// このサービスが受け入れる aud
+	AcceptedAudience string   `yaml:"accepted_audience" env:"JWT_ACCEPTED_AUDIENCE" default:"user-service"`
+	DefaultScopes    []string `yaml:"default_scopes" env:"JWT_DEFAULT_SCOPES" default:"profile:read,profile:write"`
+	// パスワード変更など重要な操作に必要な再認証の猶予時間
+	StepUpMaxAge time.Duration `yaml:"step_up_max_age" env:"STEP_UP_MAX_AGE" default:"5m"`
+}
+
+type PIIConfig struct {
+	// 個人情報の暗号化鍵ファイル（空の場合は平文で保存する）
+	KeyFile string `yaml:"key_file" env:"PII_KEY_FILE"`
+}
+
+type LoginConfig struct {
+	// IP帯域ごとの位置情報CSV（空の場合は位置情報を使わない）
+	GeoIPDBPath         string  `yaml:"geoip_db_path" env:"GEOIP_DB_PATH"`
+	MaxTravelSpeedKmh   float64 `yaml:"max_travel_speed_kmh" env:"LOGIN_MAX_TRAVEL_SPEED_KMH" default:"1000"`
+	RequireMFAOnAnomaly bool    `yaml:"require_mfa_on_anomaly" env:"LOGIN_REQUIRE_MFA_ON_ANOMALY" default:"false"`
+}
+
+type EventsConfig struct {
+	// nats / inprocess / file / memory
+	Publisher string `yaml:"publisher" env:"EVENT_PUBLISHER" default:"file"`
+	FilePath  string `yaml:"file_path" env:"EVENT_FILE_PATH" default:"events.jsonl"`
+	// CloudEvents の source 属性
+	Source            string `yaml:"source" env:"EVENT_SOURCE" default:"/user-service"`
+	NATSURL           string `yaml:"nats_url" env:"NATS_URL" default:"nats://localhost:4222"`
+	NATSStream        string `yaml:"nats_stream" env:"NATS_STREAM" default:"USER_EVENTS"`
+	NATSSubjectPrefix string `yaml:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX" default:"events"`
+}
+
+type RedisConfig struct {
+	Host     string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port     int    `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB" default:"0"`
+}
+
+type HealthConfig struct {
+	// 依存先ごとの確認のタイムアウト
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	// Redis を準備完了の確認に含めるか
+	CheckRedis bool `yaml:"check_redis" env:"HEALTH_CHECK_REDIS" default:"false"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	// memory（レプリカごと） / redis（レプリカ間で共有）
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND" default:"memory"`
+	// 登録・ログイン・再認証の制限（IPアドレスごと、再認証はユーザーごと）
+	AuthRequests int           `yaml:"auth_requests" env:"RATE_LIMIT_AUTH_REQUESTS" default:"10"`
+	AuthWindow   time.Duration `yaml:"auth_window" env:"RATE_LIMIT_AUTH_WINDOW" default:"1m"`
+	// その他の認証済みのエンドポイントの制限（ユーザーごと）
+	DefaultRequests int           `yaml:"default_requests" env:"RATE_LIMIT_DEFAULT_REQUESTS" default:"100"`
+	DefaultWindow   time.Duration `yaml:"default_window" env:"RATE_LIMIT_DEFAULT_WINDOW" default:"1m"`
+}
+
+type I18nConfig struct {
+	// Accept-Language に対応する言語がなく、ユーザーの希望もない場合の言語（ja / en）
+	DefaultLocale string `yaml:"default_locale" env:"DEFAULT_LOCALE" default:"en"`
+}
+
+type LogConfig struct {
+	// debug / info / warn / error
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	// json / text
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+}
+
+type TracingConfig struct {
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" default:"user-service"`
+	// none / stdout / file / otlp-grpc / otlp-http
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none"`
+	FilePath string `yaml:"file_path" env:"OTEL_TRACES_FILE" default:"traces.jsonl"`
+	// OTLP の送信先（host:port）
+	Endpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" default:"localhost:4317"`
+	Insecure bool   `yaml:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE" default:"true"`
+	// サンプリングする割合（0〜1）
+	SampleRatio float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+}
+
+type PaginationConfig struct {
+	// 一覧のカーソルの署名鍵（空の場合は JWT の署名鍵を使う）
+	CursorSecret string `yaml:"cursor_secret" env:"CURSOR_SECRET" secret:"true"`
+}
+
+// Redis の接続先（host:port）
+func (c RedisConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// 本番環境か
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// カーソルの署名鍵
+func (c *Config) CursorSecret() string {
+	if c.Pagination.CursorSecret != "" {
+		return c.Pagination.CursorSecret
+	}
+	return c.JWT.Secret
+}
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 環境変数の代わりに map から値を返す
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(Options{LookupEnv: lookupFrom(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Env != EnvDevelopment || cfg.Server.Port != 8080 {
+		t.Errorf("Env, Port = %q, %d", cfg.Env, cfg.Server.Port)
+	}
+	if cfg.JWT.Expiration != 24*time.Hour || cfg.JWT.StepUpMaxAge != 5*time.Minute {
+		t.Errorf("JWT durations = %s, %s", cfg.JWT.Expiration, cfg.JWT.StepUpMaxAge)
+	}
+	if got := strings.Join(cfg.JWT.DefaultScopes, ","); got != "profile:read,profile:write" {
+		t.Errorf("DefaultScopes = %q", got)
+	}
+	if cfg.Login.MaxTravelSpeedKmh != 1000 {
+		t.Errorf("MaxTravelSpeedKmh = %v", cfg.Login.MaxTravelSpeedKmh)
+	}
+	// カーソルの署名鍵は未設定の場合 JWT の署名鍵を使う
+	if cfg.CursorSecret() != cfg.JWT.Secret {
+		t.Errorf("CursorSecret() = %q", cfg.CursorSecret())
+	}
+	if cfg.Sources["PORT"] != SourceDefault {
+		t.Errorf("Sources[PORT] = %q", cfg.Sources["PORT"])
+	}
+}
+
+func TestLoad_Env(t *testing.T) {
+	cfg, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"PORT":                         "9090",
+		"JWT_EXPIRATION":               "1h",
+		"JWT_AUDIENCE":                 "user-service, order-service",
+		"LOGIN_REQUIRE_MFA_ON_ANOMALY": "true",
+		"REDIS_DB":                     "3",
+		// 空の値は未設定として扱う
+		"DB_HOST": "",
+	})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != 9090 || cfg.JWT.Expiration != time.Hour || cfg.Redis.DB != 3 {
+		t.Errorf("Port, Expiration, RedisDB = %d, %s, %d", cfg.Server.Port, cfg.JWT.Expiration, cfg.Redis.DB)
+	}
+	if got := strings.Join(cfg.JWT.Audience, "|"); got != "user-service|order-service" {
+		t.Errorf("Audience = %q", got)
+	}
+	if !cfg.Login.RequireMFAOnAnomaly {
+		t.Error("RequireMFAOnAnomaly = false")
+	}
+	if cfg.Database.Host != "localhost" {
+		t.Errorf("Database.Host = %q", cfg.Database.Host)
+	}
+	if cfg.Sources["PORT"] != SourceEnv {
+		t.Errorf("Sources[PORT] = %q", cfg.Sources["PORT"])
+	}
+}
+
+func TestLoad_EmptyValues(t *testing.T) {
+	cfg, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"JWT_DEFAULT_SCOPES":     "",
+		"SERVER_TRUSTED_PROXIES": "",
+		"REDIS_PASSWORD":         "",
+		"DB_PASSWORD":            "",
+	})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// 一覧とシークレットの空の値は既定値に戻さない
+	if len(cfg.JWT.DefaultScopes) != 0 || len(cfg.Server.TrustedProxies) != 0 {
+		t.Errorf("DefaultScopes, TrustedProxies = %v, %v, want empty", cfg.JWT.DefaultScopes, cfg.Server.TrustedProxies)
+	}
+	if cfg.Database.Password != "" || cfg.Redis.Password != "" {
+		t.Errorf("Database.Password, Redis.Password = %q, %q, want empty", cfg.Database.Password, cfg.Redis.Password)
+	}
+	for _, key := range []string{"JWT_DEFAULT_SCOPES", "SERVER_TRUSTED_PROXIES", "REDIS_PASSWORD", "DB_PASSWORD"} {
+		if cfg.Sources[key] != SourceEnv {
+			t.Errorf("Sources[%s] = %q, want %q", key, cfg.Sources[key], SourceEnv)
+		}
+	}
+
+	// 空の JWT_SECRET は既定値に戻さず、検証で拒否する
+	if _, err := Load(Options{LookupEnv: lookupFrom(map[string]string{"JWT_SECRET": ""})}); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Errorf("Load(JWT_SECRET=\"\") error = %v", err)
+	}
+}
+
+func TestLoad_InvalidValue(t *testing.T) {
+	// 型の誤りは黙って既定値に戻さない
+	for key, value := range map[string]string{
+		"JWT_EXPIRATION":               "24",
+		"PORT":                         "http",
+		"LOGIN_REQUIRE_MFA_ON_ANOMALY": "yes please",
+	} {
+		_, err := Load(Options{LookupEnv: lookupFrom(map[string]string{key: value})})
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Load(%s=%q) error = %v", key, value, err)
+		}
+	}
+}
+
+func TestLoad_SecretFile(t *testing.T) {
+	path := writeFile(t, "jwt_secret", "secret-from-file\n")
+
+	cfg, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"JWT_SECRET":      "secret-from-env",
+		"JWT_SECRET_FILE": path,
+	})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// ファイルが優先され、末尾の改行は取り除かれる
+	if cfg.JWT.Secret != "secret-from-file" {
+		t.Errorf("JWT.Secret = %q", cfg.JWT.Secret)
+	}
+	if cfg.Sources["JWT_SECRET"] != SourceFile {
+		t.Errorf("Sources[JWT_SECRET] = %q", cfg.Sources["JWT_SECRET"])
+	}
+
+	_, err = Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"JWT_SECRET_FILE": filepath.Join(t.TempDir(), "missing"),
+	})})
+	if err == nil {
+		t.Error("Load() with a missing secret file should fail")
+	}
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 7000
+jwt:
+  expiration: 2h
+  audience: [user-service, admin]
+log:
+  level: warn
+`)
+
+	cfg, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"CONFIG_FILE": path,
+		// 環境変数は YAML より優先される
+		"LOG_LEVEL": "error",
+	})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != 7000 || cfg.JWT.Expiration != 2*time.Hour || cfg.Log.Level != "error" {
+		t.Errorf("Port, Expiration, LogLevel = %d, %s, %q", cfg.Server.Port, cfg.JWT.Expiration, cfg.Log.Level)
+	}
+	if got := strings.Join(cfg.JWT.Audience, ","); got != "user-service,admin" {
+		t.Errorf("Audience = %q", got)
+	}
+	if cfg.Sources["PORT"] != SourceYAML || cfg.Sources["LOG_LEVEL"] != SourceEnv || cfg.Sources["DB_HOST"] != SourceDefault {
+		t.Errorf("Sources = %v", cfg.Sources)
+	}
+
+	// 未知の項目は誤記として扱う
+	unknown := writeFile(t, "unknown.yaml", "server:\n  prot: 7000\n")
+	if _, err := Load(Options{YAMLFile: unknown, LookupEnv: lookupFrom(nil)}); err == nil {
+		t.Error("Load() with an unknown YAML field should fail")
+	}
+}
+
+func TestLoad_DotEnv(t *testing.T) {
+	path := writeFile(t, ".env", "REDIS_HOST=redis.internal\n")
+	t.Setenv("REDIS_HOST", "")
+	os.Unsetenv("REDIS_HOST")
+
+	cfg, err := Load(Options{DotEnvFiles: []string{path, filepath.Join(t.TempDir(), "missing.env")}})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Redis.Host != "redis.internal" {
+		t.Errorf("Redis.Host = %q", cfg.Redis.Host)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"ENV":                    "prod",
+		"PORT":                   "70000",
+		"GRPC_ENABLED":           "true",
+		"GRPC_PORT":              "0",
+		"EVENT_PUBLISHER":        "kafka",
+		"LOG_LEVEL":              "verbose",
+		"STEP_UP_MAX_AGE":        "-1m",
+		"RATE_LIMIT_BACKEND":     "memcached",
+		"DEFAULT_LOCALE":         "fr",
+		"SERVER_TRUSTED_PROXIES": "10.0.0.0/8, not-an-ip",
+	})})
+	if err == nil {
+		t.Fatal("Load() should fail")
+	}
+	// すべての誤りをまとめて報告する
+	for _, key := range []string{"ENV", "PORT", "GRPC_PORT", "EVENT_PUBLISHER", "LOG_LEVEL", "STEP_UP_MAX_AGE", "RATE_LIMIT_BACKEND", "SERVER_TRUSTED_PROXIES", "DEFAULT_LOCALE"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+}
+
+func TestValidate_GRPCPort(t *testing.T) {
+	// HTTPサーバーと同じポートでは起動できない
+	_, err := Load(Options{LookupEnv: lookupFrom(map[string]string{"PORT": "50051", "GRPC_ENABLED": "true"})})
+	if err == nil || !strings.Contains(err.Error(), "GRPC_PORT must differ from PORT") {
+		t.Errorf("Load() error = %v", err)
+	}
+
+	// 無効にした場合は検証しない
+	if _, err := Load(Options{LookupEnv: lookupFrom(map[string]string{"PORT": "50051", "GRPC_ENABLED": "false"})}); err != nil {
+		t.Errorf("Load() with gRPC disabled error = %v", err)
+	}
+}
+
+func TestValidate_Production(t *testing.T) {
+	strongSecret := strings.Repeat("s", minJWTSecretLength)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "default jwt secret",
+			env:     map[string]string{"DB_PASSWORD": "strong-password"},
+			wantErr: "JWT_SECRET must not be the default value",
+		},
+		{
+			name:    "short jwt secret",
+			env:     map[string]string{"JWT_SECRET": "short", "DB_PASSWORD": "strong-password"},
+			wantErr: "JWT_SECRET must be at least",
+		},
+		{
+			name:    "default db password",
+			env:     map[string]string{"JWT_SECRET": strongSecret},
+			wantErr: "DB_PASSWORD must not be the default value",
+		},
+		{
+			name:    "sqlite",
+			env:     map[string]string{"JWT_SECRET": strongSecret, "DB_DRIVER": "sqlite"},
+			wantErr: "DB_DRIVER=sqlite",
+		},
+		{
+			name: "secure",
+			env:  map[string]string{"JWT_SECRET": strongSecret, "DB_PASSWORD": "strong-password"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env["ENV"] = EnvProduction
+			_, err := Load(Options{LookupEnv: lookupFrom(tt.env)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_ProductionDefaults(t *testing.T) {
+	secure := map[string]string{
+		"ENV":         EnvProduction,
+		"JWT_SECRET":  strings.Repeat("s", minJWTSecretLength),
+		"DB_PASSWORD": "strong-password",
+	}
+
+	// production では API ドキュメントを既定で公開しない
+	cfg, err := Load(Options{LookupEnv: lookupFrom(secure)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.DocsEnabled {
+		t.Error("DocsEnabled = true in production by default")
+	}
+
+	// 明示的に有効にした場合は公開する
+	secure["API_DOCS_ENABLED"] = "true"
+	cfg, err = Load(Options{LookupEnv: lookupFrom(secure)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Server.DocsEnabled {
+		t.Error("DocsEnabled = false with API_DOCS_ENABLED=true")
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg, err := Load(Options{LookupEnv: lookupFrom(map[string]string{
+		"JWT_SECRET":  "super-secret-value",
+		"DB_PASSWORD": "db-secret-value",
+	})})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	out := buf.String()
+
+	if strings.Contains(out, "super-secret-value") || strings.Contains(out, "db-secret-value") {
+		t.Errorf("Print() leaked a secret:\n%s", out)
+	}
+	for _, want := range []string{
+		"JWT_SECRET=[REDACTED] # env",
+		"PORT=8080 # default",
+		"JWT_EXPIRATION=24h0m0s # default",
+		"JWT_DEFAULT_SCOPES=profile:read,profile:write # default",
+		// 空のシークレットは未設定であることが分かるようにする
+		"REDIS_PASSWORD= # default",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Print() output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// 設定値の読み込み元
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceYAML    Source = "yaml"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+)
+
+// 読み込みの設定
+type Options struct {
+	// YAMLファイルのパス（空の場合は CONFIG_FILE 環境変数、それも空の場合は読み込まない）
+	YAMLFile string
+	// .env ファイルのパス（存在しない場合は無視する）
+	DotEnvFiles []string
+	// 環境変数の取得（テスト用、nil の場合は os.LookupEnv）
+	LookupEnv func(key string) (string, bool)
+}
+
+// 設定の読み込み結果
+type Loaded struct {
+	*Config
+	// 環境変数名ごとの読み込み元
+	Sources map[string]Source
+}
+
+// 設定を読み込み、検証する
+func Load(opts Options) (*Loaded, error) {
+	lookup := opts.LookupEnv
+	if lookup == nil {
+		// .env は既存の環境変数を上書きしない
+		for _, path := range opts.DotEnvFiles {
+			if err := godotenv.Load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to load %s: %w", path, err)
+			}
+		}
+		lookup = os.LookupEnv
+	}
+
+	cfg := &Config{}
+	sources := make(map[string]Source)
+
+	// 1. 既定値
+	for _, f := range fieldsOf(cfg) {
+		if f.def == "" {
+			continue
+		}
+		if err := setValue(f.value, f.def); err != nil {
+			return nil, fmt.Errorf("invalid default for %s: %w", f.env, err)
+		}
+		sources[f.env] = SourceDefault
+	}
+
+	// 2. YAMLファイル
+	yamlFile := opts.YAMLFile
+	if yamlFile == "" {
+		yamlFile, _ = lookup("CONFIG_FILE")
+	}
+	if yamlFile != "" {
+		if err := loadYAML(cfg, yamlFile, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 環境変数（<名前>_FILE はファイルの内容を値として使う）
+	for _, f := range fieldsOf(cfg) {
+		raw, source, ok, err := lookupValue(lookup, f)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.env, err)
+		}
+		sources[f.env] = source
+	}
+
+	// 4. 環境ごとの既定値（明示的に設定した値は変えない）
+	if cfg.IsProduction() && sources["API_DOCS_ENABLED"] == SourceDefault {
+		cfg.Server.DocsEnabled = false
+	}
+
+	// 5. 検証
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Loaded{Config: cfg, Sources: sources}, nil
+}
+
+func loadYAML(cfg *Config, path string, sources map[string]Source) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAMLに書かれた項目を判別するため、読み込み前の値と比べる
+	before := snapshot(cfg)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for _, f := range fieldsOf(cfg) {
+		if !reflect.DeepEqual(before[f.env], f.value.Interface()) {
+			sources[f.env] = SourceYAML
+		}
+	}
+	return nil
+}
+
+func lookupValue(lookup func(string) (string, bool), f field) (string, Source, bool, error) {
+	if f.secret {
+		if path, ok := lookup(f.env + "_FILE"); ok && path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", "", false, fmt.Errorf("failed to read %s_FILE: %w", f.env, err)
+			}
+			return strings.TrimRight(string(data), "\r\n"), SourceFile, true, nil
+		}
+	}
+	// 空の値は未設定として扱う
+	// ただし一覧とシークレットは空にすること自体が設定になるため（スコープなし・パスワードなしなど）、明示的な値として扱う
+	if value, ok := lookup(f.env); ok && (value != "" || f.secret || f.value.Kind() == reflect.Slice) {
+		return value, SourceEnv, true, nil
+	}
+	return "", "", false, nil
+}
+
+// env タグの付いた項目
+type field struct {
+	env    string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+// 設定の全項目を定義順に返す
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			fv := v.Field(i)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(fv)
+				continue
+			}
+			env := sf.Tag.Get("env")
+			if env == "" {
+				continue
+			}
+			fields = append(fields, field{
+				env:    env,
+				def:    sf.Tag.Get("default"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  fv,
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+// 全項目の値のコピー
+func snapshot(cfg *Config) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, f := range fieldsOf(cfg) {
+		if list, ok := f.value.Interface().([]string); ok {
+			values[f.env] = append([]string(nil), list...)
+			continue
+		}
+		values[f.env] = f.value.Interface()
+	}
+	return values
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 文字列を項目の型に変換して設定する
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// カンマ区切りの値をスライスに変換する
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
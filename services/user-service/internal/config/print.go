@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// シークレットの表示
+const redacted = "[REDACTED]"
+
+// 有効な設定を環境変数の形式で出力する（シークレットは伏せ字にする）
+func (l *Loaded) Print(w io.Writer) error {
+	for _, f := range fieldsOf(l.Config) {
+		value := formatValue(f.value.Interface())
+		if f.secret && value != "" {
+			value = redacted
+		}
+		source := l.Sources[f.env]
+		if source == "" {
+			source = SourceDefault
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s # %s\n", f.env, value, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
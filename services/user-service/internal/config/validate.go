@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+)
+
+// 本番環境で使用を禁止する既定のシークレット
+const (
+	insecureJWTSecret  = "your-secret-key"
+	insecureDBPassword = "password"
+	// HS256 の鍵として必要な長さ（バイト）
+	minJWTSecretLength = 32
+)
+
+// 設定の検証（すべての誤りをまとめて返す）
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// 1. 値の範囲
+	if !oneOf(c.Env, EnvDevelopment, EnvTest, EnvStaging, EnvProduction) {
+		invalid("ENV must be one of development, test, staging, production: %q", c.Env)
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("PORT must be between 1 and 65535: %d", c.Server.Port)
+	}
+	if c.GRPC.Enabled {
+		if c.GRPC.Port < 1 || c.GRPC.Port > 65535 {
+			invalid("GRPC_PORT must be between 1 and 65535: %d", c.GRPC.Port)
+		} else if c.GRPC.Port == c.Server.Port {
+			invalid("GRPC_PORT must differ from PORT: %d", c.GRPC.Port)
+		}
+	}
+	for _, t := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SHUTDOWN_GRACE_PERIOD", c.Server.ShutdownGracePeriod},
+	} {
+		if t.value <= 0 {
+			invalid("%s must be positive: %s", t.name, t.value)
+		}
+	}
+	if c.Server.ShutdownDrainDelay < 0 {
+		invalid("SHUTDOWN_DRAIN_DELAY must not be negative: %s", c.Server.ShutdownDrainDelay)
+	} else if c.Server.ShutdownDrainDelay >= c.Server.ShutdownGracePeriod && c.Server.ShutdownGracePeriod > 0 {
+		invalid("SHUTDOWN_DRAIN_DELAY must be shorter than SHUTDOWN_GRACE_PERIOD")
+	}
+	if !oneOf(c.Database.Driver, "postgres", "sqlite") {
+		invalid("DB_DRIVER must be postgres or sqlite: %q", c.Database.Driver)
+	}
+	if c.Database.Driver == "postgres" {
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			invalid("DB_PORT must be between 1 and 65535: %d", c.Database.Port)
+		}
+		if !oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full") {
+			invalid("DB_SSLMODE is invalid: %q", c.Database.SSLMode)
+		}
+	}
+	if c.JWT.Secret == "" {
+		invalid("JWT_SECRET is required")
+	}
+	if c.JWT.Expiration <= 0 {
+		invalid("JWT_EXPIRATION must be positive: %s", c.JWT.Expiration)
+	}
+	if c.JWT.StepUpMaxAge <= 0 {
+		invalid("STEP_UP_MAX_AGE must be positive: %s", c.JWT.StepUpMaxAge)
+	}
+	if c.Login.MaxTravelSpeedKmh <= 0 {
+		invalid("LOGIN_MAX_TRAVEL_SPEED_KMH must be positive: %v", c.Login.MaxTravelSpeedKmh)
+	}
+	if !oneOf(c.Events.Publisher, "nats", "inprocess", "file", "memory") {
+		invalid("EVENT_PUBLISHER must be one of nats, inprocess, file, memory: %q", c.Events.Publisher)
+	}
+	if c.Redis.Port < 1 || c.Redis.Port > 65535 {
+		invalid("REDIS_PORT must be between 1 and 65535: %d", c.Redis.Port)
+	}
+	if c.Redis.DB < 0 {
+		invalid("REDIS_DB must not be negative: %d", c.Redis.DB)
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		invalid("DB_SLOW_QUERY_THRESHOLD must not be negative: %s", c.Database.SlowQueryThreshold)
+	}
+	if c.Health.CheckTimeout <= 0 {
+		invalid("HEALTH_CHECK_TIMEOUT must be positive: %s", c.Health.CheckTimeout)
+	}
+	if !oneOf(c.RateLimit.Backend, "memory", "redis") {
+		invalid("RATE_LIMIT_BACKEND must be memory or redis: %q", c.RateLimit.Backend)
+	}
+	if c.RateLimit.AuthRequests <= 0 || c.RateLimit.AuthWindow <= 0 {
+		invalid("RATE_LIMIT_AUTH_REQUESTS and RATE_LIMIT_AUTH_WINDOW must be positive")
+	}
+	if c.RateLimit.DefaultRequests <= 0 || c.RateLimit.DefaultWindow <= 0 {
+		invalid("RATE_LIMIT_DEFAULT_REQUESTS and RATE_LIMIT_DEFAULT_WINDOW must be positive")
+	}
+	for _, cidr := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			invalid("SERVER_TRUSTED_PROXIES contains an invalid address: %q", cidr)
+		}
+	}
+	if !i18n.Supported(c.I18n.DefaultLocale) {
+		invalid("DEFAULT_LOCALE must be one of %s: %q", strings.Join(i18n.Locales(), ", "), c.I18n.DefaultLocale)
+	}
+	if !oneOf(c.Log.Level, "debug", "info", "warn", "error") {
+		invalid("LOG_LEVEL must be one of debug, info, warn, error: %q", c.Log.Level)
+	}
+	if !oneOf(c.Log.Format, "json", "text") {
+		invalid("LOG_FORMAT must be json or text: %q", c.Log.Format)
+	}
+
+	if !oneOf(c.Tracing.Exporter, "none", "stdout", "file", "otlp-grpc", "otlp-http") {
+		invalid("OTEL_TRACES_EXPORTER must be one of none, stdout, file, otlp-grpc, otlp-http: %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1: %v", c.Tracing.SampleRatio)
+	}
+
+	// 2. 本番環境では既定のシークレットでの起動を拒否する
+	if c.IsProduction() {
+		if c.JWT.Secret == insecureJWTSecret {
+			invalid("JWT_SECRET must not be the default value in production")
+		} else if len(c.JWT.Secret) < minJWTSecretLength {
+			invalid("JWT_SECRET must be at least %d bytes in production", minJWTSecretLength)
+		}
+		if c.Database.Driver == "postgres" && c.Database.Password == insecureDBPassword {
+			invalid("DB_PASSWORD must not be the default value in production")
+		}
+		if c.Database.Driver == "sqlite" {
+			invalid("DB_DRIVER=sqlite is not allowed in production")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, candidates ...string) bool {
+	for _, c := range candidates {
+		if value == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ドメインイベントの種類
+const (
+	EventUserRegistered = "user.registered"
+	EventUserUpdated    = "user.updated"
+	EventUserDeleted    = "user.deleted"
+	EventEmailVerified  = "user.email_verified"
+)
+
+// DomainEvent インターフェース
+// イベントはアウトボックスのテーブルや外部のブローカーに保存されるため、個人情報（メールアドレス・氏名）を含めない
+// 購読側で必要な場合は、ユーザーIDで GetUser を呼び出して取得する
+type DomainEvent interface {
+	EventType() string
+	AggregateID() string
+	OccurredAt() time.Time
+}
+
+// ユーザー登録イベント
+type UserRegistered struct {
+	UserID       string    `json:"user_id"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+func (e UserRegistered) EventType() string     { return EventUserRegistered }
+func (e UserRegistered) AggregateID() string   { return e.UserID }
+func (e UserRegistered) OccurredAt() time.Time { return e.RegisteredAt }
+
+// ユーザー更新イベント
+type UserUpdated struct {
+	UserID    string    `json:"user_id"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e UserUpdated) EventType() string     { return EventUserUpdated }
+func (e UserUpdated) AggregateID() string   { return e.UserID }
+func (e UserUpdated) OccurredAt() time.Time { return e.UpdatedAt }
+
+// ユーザー削除イベント
+type UserDeleted struct {
+	UserID    string    `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func (e UserDeleted) EventType() string     { return EventUserDeleted }
+func (e UserDeleted) AggregateID() string   { return e.UserID }
+func (e UserDeleted) OccurredAt() time.Time { return e.DeletedAt }
+
+// メールアドレス確認イベント
+// メールアドレスの確認フローは未実装のため、現時点ではこのイベントを発行する処理はない
+type EmailVerified struct {
+	UserID     string    `json:"user_id"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+func (e EmailVerified) EventType() string     { return EventEmailVerified }
+func (e EmailVerified) AggregateID() string   { return e.UserID }
+func (e EmailVerified) OccurredAt() time.Time { return e.VerifiedAt }
+
+// アウトボックスに保存されたイベント
+type OutboxMessage struct {
+	ID            string
+	EventType     string
+	AggregateID   string
+	Payload       []byte
+	OccurredAt    time.Time
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	PublishedAt   *time.Time
+}
+
+// OutboxRepository インターフェース
+type OutboxRepository interface {
+	// 集約の変更と同じトランザクションでイベントを保存する
+	Add(ctx context.Context, event DomainEvent) error
+	// 送信対象のメッセージを取得する（トランザクション内で呼び出すと他のワーカーと重複しない）
+	FetchPending(ctx context.Context, limit int) ([]*OutboxMessage, error)
+	MarkPublished(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) error
+}
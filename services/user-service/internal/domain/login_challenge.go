@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationCode = errors.New("invalid or expired verification code")
+)
+
+// LoginChallenge エンティティ（不審なログインに送った確認コード）
+type LoginChallenge struct {
+	ID     string
+	UserID string
+	// 確認が済んだときに完了扱いにするログインイベント
+	LoginEventID string
+	// 確認コードのハッシュ（コード自体は保存しない）
+	CodeHash string
+	// 誤ったコードが入力された回数
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// 有効期限切れか
+func (c *LoginChallenge) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// LoginChallengeRepository インターフェース
+type LoginChallengeRepository interface {
+	// 同じユーザーの未使用の確認は置き換える
+	Create(ctx context.Context, challenge *LoginChallenge) error
+	// ユーザーの最新の確認を返す（ない場合は nil）
+	FindLatestByUserID(ctx context.Context, userID string) (*LoginChallenge, error)
+	// 誤ったコードの入力回数を1増やす
+	IncrementAttempts(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
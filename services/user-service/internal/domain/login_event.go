@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrMFARequired = errors.New("multi-factor authentication required")
+)
+
+// ログインイベントのフラグ
+const (
+	LoginFlagNewDevice        = "new_device"
+	LoginFlagImpossibleTravel = "impossible_travel"
+)
+
+// 位置情報の精度を考慮し、これ未満の移動距離は判定しない
+const minTravelDistanceKm = 100.0
+
+// 大まかな位置情報
+type GeoLocation struct {
+	Country   string
+	City      string
+	Latitude  float64
+	Longitude float64
+}
+
+// LoginEvent エンティティ
+type LoginEvent struct {
+	ID        string
+	UserID    string
+	IPAddress string
+	UserAgent string
+	// ユーザーエージェントから算出した端末識別子
+	DeviceID string
+	// 位置情報が取得できない場合は nil
+	Location *GeoLocation
+	Flags    []string
+	// MFA要求などによりログインが完了しなかった場合は false（本人確認が済むと true になる）
+	Succeeded bool
+	CreatedAt time.Time
+}
+
+// 不審なログインか
+func (e *LoginEvent) Suspicious() bool {
+	return len(e.Flags) > 0
+}
+
+// 指定したフラグが付いているか
+func (e *LoginEvent) HasFlag(flag string) bool {
+	for _, f := range e.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// 過去のログイン履歴と比較して不審な点を検出する
+// history は新しい順に並んでいること
+// 完了しなかったログイン（MFA要求など）は信頼できる端末・位置として扱わない
+func DetectLoginAnomalies(event *LoginEvent, history []*LoginEvent, maxTravelSpeedKmh float64) []string {
+	var succeeded []*LoginEvent
+	for _, h := range history {
+		if h.Succeeded {
+			succeeded = append(succeeded, h)
+		}
+	}
+	history = succeeded
+
+	// 初回ログインは比較対象がないため判定しない
+	if len(history) == 0 {
+		return nil
+	}
+
+	var flags []string
+
+	// 1. 新しい端末の判定
+	knownDevice := false
+	for _, h := range history {
+		if h.DeviceID == event.DeviceID {
+			knownDevice = true
+			break
+		}
+	}
+	if !knownDevice {
+		flags = append(flags, LoginFlagNewDevice)
+	}
+
+	// 2. 移動不可能な距離からのログインの判定（直近の位置情報付きログインと比較）
+	if event.Location != nil {
+		for _, h := range history {
+			if h.Location == nil {
+				continue
+			}
+			distance := haversineKm(*h.Location, *event.Location)
+			hours := event.CreatedAt.Sub(h.CreatedAt).Hours()
+			if distance >= minTravelDistanceKm && (hours <= 0 || distance/hours > maxTravelSpeedKmh) {
+				flags = append(flags, LoginFlagImpossibleTravel)
+			}
+			break
+		}
+	}
+
+	return flags
+}
+
+// 2地点間の大円距離（km）
+func haversineKm(a, b GeoLocation) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.Latitude - a.Latitude)
+	dLon := toRad(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Latitude))*math.Cos(toRad(b.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// LoginEventRepository インターフェース
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *LoginEvent) error
+	// 完了したログインのみを新しい順に最大 limit 件返す
+	FindRecentSucceededByUserID(ctx context.Context, userID string, limit int) ([]*LoginEvent, error)
+	// 確認コードによる本人確認が済んだログインを完了扱いにする
+	MarkSucceeded(ctx context.Context, id string) error
+}
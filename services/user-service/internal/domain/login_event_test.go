@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectLoginAnomalies(t *testing.T) {
+	now := time.Now()
+	tokyo := &GeoLocation{Country: "JP", City: "Tokyo", Latitude: 35.68, Longitude: 139.69}
+	osaka := &GeoLocation{Country: "JP", City: "Osaka", Latitude: 34.69, Longitude: 135.50}
+	london := &GeoLocation{Country: "GB", City: "London", Latitude: 51.51, Longitude: -0.13}
+
+	tests := []struct {
+		name    string
+		event   *LoginEvent
+		history []*LoginEvent
+		want    []string
+	}{
+		{
+			name:    "first login is never flagged",
+			event:   &LoginEvent{DeviceID: "a", Location: london, CreatedAt: now},
+			history: nil,
+			want:    nil,
+		},
+		{
+			name:  "known device from same city",
+			event: &LoginEvent{DeviceID: "a", Location: tokyo, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+			},
+			want: nil,
+		},
+		{
+			name:  "new device",
+			event: &LoginEvent{DeviceID: "b", Location: tokyo, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+			},
+			want: []string{LoginFlagNewDevice},
+		},
+		{
+			name:  "impossible travel",
+			event: &LoginEvent{DeviceID: "a", Location: london, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+			},
+			want: []string{LoginFlagImpossibleTravel},
+		},
+		{
+			name:  "plausible travel by train",
+			event: &LoginEvent{DeviceID: "a", Location: osaka, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-3 * time.Hour), Succeeded: true},
+			},
+			want: nil,
+		},
+		{
+			name:  "compares with latest located login only",
+			event: &LoginEvent{DeviceID: "a", Location: london, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: nil, CreatedAt: now.Add(-time.Minute), Succeeded: true},
+				{DeviceID: "a", Location: london, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-2 * time.Hour), Succeeded: true},
+			},
+			want: nil,
+		},
+		{
+			// MFAを要求して完了しなかったログインと同じ端末・位置からの再試行も検出する
+			name:  "ignores logins that did not complete",
+			event: &LoginEvent{DeviceID: "b", Location: london, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "b", Location: london, CreatedAt: now.Add(-time.Minute), Flags: []string{LoginFlagNewDevice, LoginFlagImpossibleTravel}},
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+			},
+			want: []string{LoginFlagNewDevice, LoginFlagImpossibleTravel},
+		},
+		{
+			name:  "new device and impossible travel",
+			event: &LoginEvent{DeviceID: "b", Location: london, CreatedAt: now},
+			history: []*LoginEvent{
+				{DeviceID: "a", Location: tokyo, CreatedAt: now.Add(-time.Hour), Succeeded: true},
+			},
+			want: []string{LoginFlagNewDevice, LoginFlagImpossibleTravel},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectLoginAnomalies(tt.event, tt.history, 1000)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectLoginAnomalies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
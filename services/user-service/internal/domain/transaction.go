@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// TxManager トランザクション境界のインターフェース
+// fn に渡されたコンテキストを使うリポジトリ操作は同じトランザクションで実行される
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -13,18 +13,88 @@ var (
 	ErrEmailAlreadyExists = errors.New("email already exists")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserNotFound       = errors.New("user not found")
+	// 読み込み後に他の更新が行われていた
+	ErrConcurrentModification = errors.New("resource was modified concurrently")
+
+	// 永続化層の制約違反・競合を表すエラー
+	ErrConflict             = errors.New("resource conflict")
+	ErrReferenceViolation   = errors.New("referenced resource does not exist")
+	ErrConstraintViolation  = errors.New("data violates a constraint")
+	ErrSerializationFailure = errors.New("transaction could not be serialized")
 )
 
 // User エンティティ
 type User struct {
-	ID        string
-	Email     string
-	Password  string
-	Name      string
+	ID       string
+	Email    string
+	Password string
+	Name     string
+	// 希望する言語（空の場合はリクエストの Accept-Language に従う）
+	Locale string
+	// 楽観的排他制御のバージョン（作成時は1、更新ごとに1増える）
+	Version   int
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// ユーザー一覧の並び順・絞り込みに使えるフィールド
+const (
+	UserFieldEmail     = "email"
+	UserFieldName      = "name"
+	UserFieldCreatedAt = "created_at"
+)
+
+// ユーザー一覧の絞り込みの演算子
+type UserFilterOperator string
+
+const (
+	UserFilterEq       UserFilterOperator = "eq"
+	UserFilterNe       UserFilterOperator = "ne"
+	UserFilterLt       UserFilterOperator = "lt"
+	UserFilterLte      UserFilterOperator = "lte"
+	UserFilterGt       UserFilterOperator = "gt"
+	UserFilterGte      UserFilterOperator = "gte"
+	UserFilterContains UserFilterOperator = "contains"
+	UserFilterPrefix   UserFilterOperator = "prefix"
+)
+
+// ユーザー一覧の絞り込み条件
+type UserFilter struct {
+	Field    string
+	Operator UserFilterOperator
+	// メールアドレス・氏名の場合は string、作成日時の場合は time.Time
+	Value interface{}
+}
+
+// ユーザー一覧の位置（並び順キーの値とID）
+type UserListPosition struct {
+	Value string
+	ID    string
+}
+
+// ユーザー一覧の取得条件
+type UserListParams struct {
+	Limit     int
+	SortField string
+	// 取得する順序（前のページを取得する場合は表示順と逆になる）
+	Desc    bool
+	Filters []UserFilter
+	// この位置より後ろの要素を取得する（先頭から取得する場合は nil）
+	After *UserListPosition
+}
+
+// 並び順キーの値（カーソルに保存される）
+func (u *User) SortValue(field string) string {
+	switch field {
+	case UserFieldEmail:
+		return u.Email
+	case UserFieldName:
+		return u.Name
+	default:
+		return u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
 // ドメインのビジネスルール
 func (u *User) Validate() error {
 	// メールアドレスの検証
@@ -40,6 +110,22 @@ func (u *User) Validate() error {
 	return nil
 }
 
+// メールアドレスの形式の検証
+func ValidateEmail(email string) error {
+	if !isValidEmail(email) {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// 平文パスワードの強度検証
+func ValidatePassword(password string) error {
+	if !isStrongPassword(password) {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
 // メールアドレスのバリデーション
 func isValidEmail(email string) bool {
 	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
@@ -65,7 +151,11 @@ func isStrongPassword(password string) bool {
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	FindByID(ctx context.Context, id string) (*User, error)
+	// 見つかったユーザーのみを返す（順序は保証しない）
+	FindByIDs(ctx context.Context, ids []string) ([]*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id string) error
+	// 並び順キーとIDによるキーセット方式で params.Desc の順に最大 params.Limit 件を返す
+	List(ctx context.Context, params UserListParams) ([]*User, error)
 }
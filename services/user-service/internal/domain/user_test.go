@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUser_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "valid", email: "taro@example.com", password: "Passw0rd", wantErr: nil},
+		{name: "valid with plus and subdomain", email: "taro+shop@mail.example.co.jp", password: "Passw0rd", wantErr: nil},
+		{name: "missing at sign", email: "taro.example.com", password: "Passw0rd", wantErr: ErrInvalidEmail},
+		{name: "missing domain", email: "taro@", password: "Passw0rd", wantErr: ErrInvalidEmail},
+		{name: "short tld", email: "taro@example.c", password: "Passw0rd", wantErr: ErrInvalidEmail},
+		{name: "empty email", email: "", password: "Passw0rd", wantErr: ErrInvalidEmail},
+		{name: "too short password", email: "taro@example.com", password: "Pa0rd", wantErr: ErrWeakPassword},
+		{name: "no uppercase", email: "taro@example.com", password: "passw0rd", wantErr: ErrWeakPassword},
+		{name: "no lowercase", email: "taro@example.com", password: "PASSW0RD", wantErr: ErrWeakPassword},
+		{name: "no digit", email: "taro@example.com", password: "Password", wantErr: ErrWeakPassword},
+		{name: "invalid email takes precedence", email: "invalid", password: "weak", wantErr: ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Email: tt.email, Password: tt.password}
+			if err := u.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		wantErr  error
+	}{
+		{password: "Passw0rd", wantErr: nil},
+		{password: "LongerPassw0rdIsFine", wantErr: nil},
+		{password: "Passw0r", wantErr: ErrWeakPassword},
+		{password: "password1", wantErr: ErrWeakPassword},
+		{password: "", wantErr: ErrWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			if err := ValidatePassword(tt.password); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidatePassword(%q) error = %v, want %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// services/user-service/internal/i18n/i18n.go
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// 対応している言語
+const (
+	Japanese = "ja"
+	English  = "en"
+)
+
+// カタログにないメッセージの代わりに使う言語（メッセージの原文の言語）
+const fallbackLocale = English
+
+// メッセージに埋め込む値（{name} の形式で参照する）
+type Params map[string]string
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+// 言語ごとのメッセージ（キーは "error.user_not_found" のようにドットで区切る）
+var catalogs = mustLoadCatalogs(localeFiles)
+
+// Accept-Language の照合（先頭はどれにも一致しない場合の候補だが、一致度で除外する）
+var (
+	supportedTags = []language.Tag{language.English, language.Japanese}
+	supportedIDs  = []string{English, Japanese}
+	matcher       = language.NewMatcher(supportedTags)
+)
+
+// 対応している言語か
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// 対応している言語の一覧
+func Locales() []string {
+	return append([]string(nil), supportedIDs...)
+}
+
+// Accept-Language ヘッダーから最も適した言語を選ぶ（対応する言語がない場合は false）
+func Match(acceptLanguage string) (string, bool) {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return "", false
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return "", false
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+	return supportedIDs[index], true
+}
+
+// メッセージの翻訳（ない場合は原文の言語、それもない場合はキーを返す）
+func T(locale, id string, params Params) string {
+	if message, ok := Lookup(locale, id, params); ok {
+		return message
+	}
+	return id
+}
+
+// メッセージの検索（指定した言語になければ原文の言語で探す）
+func Lookup(locale, id string, params Params) (string, bool) {
+	message, ok := catalogs[locale][id]
+	if !ok {
+		message, ok = catalogs[fallbackLocale][id]
+	}
+	if !ok {
+		return "", false
+	}
+	return format(message, params), true
+}
+
+// {name} を値に置き換える
+func format(message string, params Params) string {
+	if len(params) == 0 {
+		return message
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for key, value := range params {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+type localeKey struct{}
+
+// リクエストの言語をコンテキストに設定する
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// コンテキストの言語（設定されていない場合は原文の言語）
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return fallbackLocale
+}
+
+// 埋め込んだカタログの読み込み（ファイル名が言語になる）
+func mustLoadCatalogs(fsys fs.FS) map[string]map[string]string {
+	catalogs, err := loadCatalogs(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return catalogs
+}
+
+func loadCatalogs(fsys fs.FS) (map[string]map[string]string, error) {
+	files, err := fs.Glob(fsys, "locales/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		messages := make(map[string]string)
+		if err := flatten("", tree, messages); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		catalogs[strings.TrimSuffix(path.Base(file), ".yaml")] = messages
+	}
+	return catalogs, nil
+}
+
+// 入れ子のキーをドット区切りに展開する
+func flatten(prefix string, tree map[string]interface{}, messages map[string]string) error {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case string:
+			messages[key] = v
+		case map[string]interface{}:
+			if err := flatten(key, v, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q must be a string", key)
+		}
+	}
+	return nil
+}
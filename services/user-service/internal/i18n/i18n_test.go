@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		wantOK bool
+	}{
+		{header: "ja", want: Japanese, wantOK: true},
+		{header: "ja-JP,ja;q=0.9,en;q=0.8", want: Japanese, wantOK: true},
+		{header: "en-US,en;q=0.9", want: English, wantOK: true},
+		{header: "fr-FR,ja;q=0.5", want: Japanese, wantOK: true},
+		{header: "en;q=0.5,ja;q=0.9", want: Japanese, wantOK: true},
+		{header: "fr-FR"},
+		{header: ""},
+		{header: ";;;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := Match(tt.header)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Match(%q) = %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(Japanese, "error.user_not_found", nil); got != "ユーザーが見つかりません" {
+		t.Errorf("ja = %q", got)
+	}
+	if got := T(English, "validation.min_length", Params{"param": "8"}); got != "must be at least 8 characters" {
+		t.Errorf("en with params = %q", got)
+	}
+	// 未対応の言語は原文の言語で返す
+	if got := T("fr", "error.user_not_found", nil); got != "User not found" {
+		t.Errorf("fallback = %q", got)
+	}
+	if got := T(Japanese, "error.no_such_message", nil); got != "error.no_such_message" {
+		t.Errorf("missing = %q", got)
+	}
+	if _, ok := Lookup(Japanese, "error.no_such_message", nil); ok {
+		t.Error("Lookup() should report missing messages")
+	}
+}
+
+func TestLocaleFromContext(t *testing.T) {
+	if got := LocaleFromContext(context.Background()); got != English {
+		t.Errorf("default = %q, want %q", got, English)
+	}
+	if got := LocaleFromContext(WithLocale(context.Background(), Japanese)); got != Japanese {
+		t.Errorf("got %q, want %q", got, Japanese)
+	}
+}
+
+// すべての言語が同じキーと埋め込み値を持つこと
+func TestCatalogsAreComplete(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{[a-z_]+\}`)
+	placeholders := func(message string) []string {
+		found := placeholder.FindAllString(message, -1)
+		sort.Strings(found)
+		return found
+	}
+
+	source := catalogs[fallbackLocale]
+	for _, locale := range Locales() {
+		catalog, ok := catalogs[locale]
+		if !ok {
+			t.Fatalf("missing catalog for %s", locale)
+		}
+		for id, message := range source {
+			translated, ok := catalog[id]
+			if !ok {
+				t.Errorf("%s: missing %s", locale, id)
+				continue
+			}
+			if want, got := placeholders(message), placeholders(translated); len(want) != len(got) {
+				t.Errorf("%s: %s has placeholders %v, want %v", locale, id, got, want)
+			}
+		}
+		for id := range catalog {
+			if _, ok := source[id]; !ok {
+				t.Errorf("%s: %s is not in the %s catalog", locale, id, fallbackLocale)
+			}
+		}
+	}
+}
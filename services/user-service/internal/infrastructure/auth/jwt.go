@@ -2,48 +2,276 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// 定義済みスコープ
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+	// 他のユーザーの情報の参照（管理者向け）
+	ScopeUsersRead = "users:read"
+)
+
+// サービス間通信用のトークンの subject の接頭辞（ユーザーIDと区別する）
+const ServiceSubjectPrefix = "service:"
+
+// 認証方式（acr クレームの値）
+const (
+	ACRPassword = "pwd"
+	ACRMFA      = "mfa"
+)
+
+// トークン発行の方法（メトリクスのラベル）
+const (
+	IssueMethodPassword = "password"
+	IssueMethodMFA      = "mfa"
+	IssueMethodRefresh  = "refresh"
+	IssueMethodService  = "service"
+	IssueMethodOther    = "other"
+)
+
+// トークン検証の結果（メトリクスのラベル）
+const (
+	ValidationValid            = "valid"
+	ValidationExpired          = "expired"
+	ValidationInvalidSignature = "invalid_signature"
+	ValidationInvalidIssuer    = "invalid_issuer"
+	ValidationInvalidAudience  = "invalid_audience"
+	ValidationMalformed        = "malformed"
+)
+
+// トークンの発行・検証の観測（メトリクスの収集用）
+type Observer interface {
+	TokenIssued(method string)
+	TokenValidated(result string)
+}
+
+// 何もしない Observer
+type noopObserver struct{}
+
+func (noopObserver) TokenIssued(string)    {}
+func (noopObserver) TokenValidated(string) {}
+
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInvalidIssuer     = errors.New("invalid token issuer")
+	ErrInvalidAudience   = errors.New("invalid token audience")
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
 type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	// スペース区切りのスコープ（RFC 8693 の scope クレームと同じ形式）
+	Scope string `json:"scope,omitempty"`
+	// 最後にユーザーが認証情報を提示した時刻
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// 最後の認証で使われた方式
+	ACR string `json:"acr,omitempty"`
+	// ユーザーが希望する言語（OIDC の locale クレームと同じ名前）
+	Locale string `json:"locale,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// スコープの一覧を返す
+func (c *JWTClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// 最後の認証から maxAge 以内か
+func (c *JWTClaims) AuthenticatedWithin(maxAge time.Duration) bool {
+	if c.AuthTime == nil {
+		return false
+	}
+	return time.Since(c.AuthTime.Time) <= maxAge
+}
+
+// 指定したスコープをすべて持っているか
+func (c *JWTClaims) HasScopes(scopes ...string) bool {
+	granted := make(map[string]struct{})
+	for _, s := range c.Scopes() {
+		granted[s] = struct{}{}
+	}
+	for _, s := range scopes {
+		if _, ok := granted[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// JWTサービスの設定
+type Config struct {
+	SecretKey string
+	Expires   time.Duration
+	// 発行者（iss）。検証時は一致しないトークンを拒否する
+	Issuer string
+	// 発行するトークンの対象サービス（aud）
+	Audience []string
+	// このサービスが受け入れる aud。空の場合は aud を検証しない
+	AcceptedAudience string
+	// スコープ指定なしで発行する場合のデフォルトスコープ
+	DefaultScopes []string
+	// 発行・検証の観測（nil の場合は何もしない）
+	Observer Observer
+}
+
 type JWTService struct {
-	secretKey string
-	expires   time.Duration
+	secretKey        string
+	expires          time.Duration
+	issuer           string
+	audience         []string
+	acceptedAudience string
+	defaultScopes    []string
+	observer         Observer
 }
 
-func NewJWTService(secretKey string, expires time.Duration) *JWTService {
+func NewJWTService(config Config) *JWTService {
+	observer := config.Observer
+	if observer == nil {
+		observer = noopObserver{}
+	}
 	return &JWTService{
-		secretKey: secretKey,
-		expires:   expires,
+		observer:         observer,
+		secretKey:        config.SecretKey,
+		expires:          config.Expires,
+		issuer:           config.Issuer,
+		audience:         config.Audience,
+		acceptedAudience: config.AcceptedAudience,
+		defaultScopes:    config.DefaultScopes,
 	}
 }
 
-// トークンの生成
-func (s *JWTService) GenerateToken(userID, email string) (string, error) {
-	claims := &JWTClaims{
+// トークン発行のパラメータ
+type TokenParams struct {
+	UserID   string
+	Email    string
+	Scopes   []string
+	AuthTime time.Time
+	ACR      string
+	Locale   string
+	// 有効期間（0 の場合は設定の有効期間）
+	ExpiresIn time.Duration
+}
+
+// トークンの有効期間
+func (s *JWTService) ExpiresIn() time.Duration {
+	return s.expires
+}
+
+// パスワード認証直後のトークン生成（スコープ省略時はデフォルトスコープを付与）
+func (s *JWTService) GenerateToken(userID, email string, scopes ...string) (string, error) {
+	return s.GeneratePasswordToken(TokenParams{
 		UserID: userID,
 		Email:  email,
+		Scopes: scopes,
+	})
+}
+
+// パスワード認証直後のトークン生成（認証時刻と認証方式は自動で設定する）
+func (s *JWTService) GeneratePasswordToken(params TokenParams) (string, error) {
+	params.AuthTime = time.Now()
+	params.ACR = ACRPassword
+	return s.issue(params, IssueMethodPassword)
+}
+
+// 確認コードによる本人確認直後のトークン生成（認証時刻と認証方式は自動で設定する）
+func (s *JWTService) GenerateMFAToken(params TokenParams) (string, error) {
+	params.AuthTime = time.Now()
+	params.ACR = ACRMFA
+	return s.issue(params, IssueMethodMFA)
+}
+
+// サービス間通信用のトークンの生成（subject は "service:<name>"）
+// デフォルトスコープは付与しないため、必要なスコープを指定する
+func (s *JWTService) IssueServiceToken(name string, scopes []string, expiresIn time.Duration) (string, error) {
+	if name == "" || len(scopes) == 0 {
+		return "", fmt.Errorf("service token requires a name and at least one scope")
+	}
+	return s.issue(TokenParams{
+		UserID:    ServiceSubjectPrefix + name,
+		Scopes:    scopes,
+		ExpiresIn: expiresIn,
+	}, IssueMethodService)
+}
+
+// パラメータを指定したトークンの生成
+func (s *JWTService) IssueToken(params TokenParams) (string, error) {
+	return s.issue(params, IssueMethodOther)
+}
+
+func (s *JWTService) issue(params TokenParams, method string) (string, error) {
+	scopes := params.Scopes
+	if len(scopes) == 0 {
+		scopes = s.defaultScopes
+	}
+	expiresIn := params.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = s.expires
+	}
+
+	claims := &JWTClaims{
+		UserID: params.UserID,
+		Email:  params.Email,
+		Scope:  strings.Join(scopes, " "),
+		ACR:    params.ACR,
+		Locale: params.Locale,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.expires)),
+			Issuer:    s.issuer,
+			Subject:   params.UserID,
+			Audience:  jwt.ClaimStrings(s.audience),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	if !params.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(params.AuthTime)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	signed, err := token.SignedString([]byte(s.secretKey))
+	if err != nil {
+		return "", err
+	}
+	s.observer.TokenIssued(method)
+	return signed, nil
 }
 
 // トークンの検証
 func (s *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
+	claims, err := s.validate(tokenString)
+	s.observer.TokenValidated(validationResult(err))
+	return claims, err
+}
+
+// 検証エラーをメトリクスのラベルに変換する
+func validationResult(err error) string {
+	var validationErr *jwt.ValidationError
+	switch {
+	case err == nil:
+		return ValidationValid
+	case errors.Is(err, ErrInvalidIssuer):
+		return ValidationInvalidIssuer
+	case errors.Is(err, ErrInvalidAudience):
+		return ValidationInvalidAudience
+	case errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0:
+		return ValidationExpired
+	case errors.As(err, &validationErr) && validationErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+		return ValidationInvalidSignature
+	default:
+		return ValidationMalformed
+	}
+}
+
+func (s *JWTService) validate(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -55,11 +283,22 @@ func (s *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// 発行者の検証
+	if s.issuer != "" && !claims.VerifyIssuer(s.issuer, true) {
+		return nil, ErrInvalidIssuer
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	// 対象サービスの検証
+	if s.acceptedAudience != "" && !claims.VerifyAudience(s.acceptedAudience, true) {
+		return nil, ErrInvalidAudience
+	}
+
+	return claims, nil
 }
 
 // トークンの更新
@@ -69,6 +308,16 @@ func (s *JWTService) RefreshToken(tokenString string) (string, error) {
 		return "", err
 	}
 
-	// 新しいトークンの生成
-	return s.GenerateToken(claims.UserID, claims.Email)
+	// 新しいトークンの生成（スコープと認証時刻は元のトークンを引き継ぐ）
+	params := TokenParams{
+		UserID: claims.UserID,
+		Email:  claims.Email,
+		Scopes: claims.Scopes(),
+		ACR:    claims.ACR,
+		Locale: claims.Locale,
+	}
+	if claims.AuthTime != nil {
+		params.AuthTime = claims.AuthTime.Time
+	}
+	return s.issue(params, IssueMethodRefresh)
 }
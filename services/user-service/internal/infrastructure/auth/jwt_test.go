@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestService() *JWTService {
+	return NewJWTService(Config{
+		SecretKey:        "test-secret",
+		Expires:          time.Hour,
+		Issuer:           "user-service",
+		Audience:         []string{"user-service", "product-service"},
+		AcceptedAudience: "user-service",
+		DefaultScopes:    []string{ScopeProfileRead, ScopeProfileWrite},
+	})
+}
+
+func TestJWTService_GenerateAndValidate(t *testing.T) {
+	s := newTestService()
+
+	token, err := s.GenerateToken("user-1", "taro@example.com")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	if claims.UserID != "user-1" || claims.Email != "taro@example.com" || claims.Subject != "user-1" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if claims.Issuer != "user-service" {
+		t.Errorf("Issuer = %q", claims.Issuer)
+	}
+	if !reflect.DeepEqual([]string(claims.Audience), []string{"user-service", "product-service"}) {
+		t.Errorf("Audience = %v", claims.Audience)
+	}
+	if !reflect.DeepEqual(claims.Scopes(), []string{ScopeProfileRead, ScopeProfileWrite}) {
+		t.Errorf("Scopes() = %v, want default scopes", claims.Scopes())
+	}
+	if claims.ACR != ACRPassword || !claims.AuthenticatedWithin(time.Minute) {
+		t.Errorf("ACR = %q, AuthTime = %v", claims.ACR, claims.AuthTime)
+	}
+}
+
+func TestJWTService_GenerateTokenWithScopes(t *testing.T) {
+	s := newTestService()
+
+	token, err := s.GenerateToken("user-1", "taro@example.com", ScopeProfileRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claims.HasScopes(ScopeProfileRead) {
+		t.Error("HasScopes(profile:read) = false")
+	}
+	if claims.HasScopes(ScopeProfileRead, ScopeProfileWrite) {
+		t.Error("HasScopes(profile:read, profile:write) = true, want false")
+	}
+}
+
+func TestJWTService_IssueServiceToken(t *testing.T) {
+	s := newTestService()
+
+	token, err := s.IssueServiceToken("order-service", []string{ScopeUsersRead}, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueServiceToken() error = %v", err)
+	}
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.Subject != "service:order-service" || claims.UserID != "service:order-service" {
+		t.Errorf("Subject = %q, UserID = %q", claims.Subject, claims.UserID)
+	}
+	if !reflect.DeepEqual(claims.Scopes(), []string{ScopeUsersRead}) {
+		t.Errorf("Scopes() = %v, want [%s]", claims.Scopes(), ScopeUsersRead)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != 10*time.Minute {
+		t.Errorf("lifetime = %v, want 10m", lifetime)
+	}
+
+	// デフォルトスコープは付与しない
+	if _, err := s.IssueServiceToken("order-service", nil, time.Minute); err == nil {
+		t.Error("IssueServiceToken() without scopes succeeded")
+	}
+}
+
+func TestJWTService_ValidateTokenRejects(t *testing.T) {
+	s := newTestService()
+
+	sign := func(t *testing.T, config Config) string {
+		t.Helper()
+		token, err := NewJWTService(config).GenerateToken("user-1", "taro@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr error
+	}{
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				return sign(t, Config{SecretKey: "test-secret", Expires: time.Hour, Issuer: "other-service", Audience: []string{"user-service"}})
+			},
+			wantErr: ErrInvalidIssuer,
+		},
+		{
+			name: "token for another service",
+			token: func(t *testing.T) string {
+				return sign(t, Config{SecretKey: "test-secret", Expires: time.Hour, Issuer: "user-service", Audience: []string{"product-service"}})
+			},
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name: "wrong secret",
+			token: func(t *testing.T) string {
+				return sign(t, Config{SecretKey: "other-secret", Expires: time.Hour, Issuer: "user-service", Audience: []string{"user-service"}})
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				return sign(t, Config{SecretKey: "test-secret", Expires: -time.Minute, Issuer: "user-service", Audience: []string{"user-service"}})
+			},
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodNone, &JWTClaims{UserID: "user-1"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+		},
+		{
+			name:  "malformed",
+			token: func(t *testing.T) string { return "not-a-token" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ValidateToken(tt.token(t))
+			if err == nil {
+				t.Fatal("ValidateToken() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTService_RefreshTokenKeepsAuthContext(t *testing.T) {
+	s := newTestService()
+	authTime := time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+
+	token, err := s.IssueToken(TokenParams{
+		UserID:   "user-1",
+		Email:    "taro@example.com",
+		Scopes:   []string{ScopeProfileRead},
+		AuthTime: authTime,
+		ACR:      ACRMFA,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := s.RefreshToken(token)
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err)
+	}
+	claims, err := s.ValidateToken(refreshed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !claims.AuthTime.Time.Equal(authTime) {
+		t.Errorf("AuthTime = %v, want %v", claims.AuthTime.Time, authTime)
+	}
+	if claims.ACR != ACRMFA {
+		t.Errorf("ACR = %q, want %q", claims.ACR, ACRMFA)
+	}
+	if !reflect.DeepEqual(claims.Scopes(), []string{ScopeProfileRead}) {
+		t.Errorf("Scopes() = %v", claims.Scopes())
+	}
+	if claims.AuthenticatedWithin(5 * time.Minute) {
+		t.Error("AuthenticatedWithin(5m) = true after refresh, want false")
+	}
+}
+
+// 記録した発行・検証（テスト用）
+type recordingObserver struct {
+	issued    []string
+	validated []string
+}
+
+func (o *recordingObserver) TokenIssued(method string)    { o.issued = append(o.issued, method) }
+func (o *recordingObserver) TokenValidated(result string) { o.validated = append(o.validated, result) }
+
+func TestJWTService_Observer(t *testing.T) {
+	observer := &recordingObserver{}
+	s := NewJWTService(Config{
+		SecretKey:        "test-secret",
+		Expires:          time.Hour,
+		Issuer:           "user-service",
+		AcceptedAudience: "user-service",
+		Audience:         []string{"user-service"},
+		Observer:         observer,
+	})
+
+	token, err := s.GenerateToken("user-1", "taro@example.com")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if _, err := s.RefreshToken(token); err != nil {
+		t.Fatalf("RefreshToken() error = %v", err)
+	}
+
+	expired := NewJWTService(Config{SecretKey: "test-secret", Expires: -time.Minute})
+	expiredToken, _ := expired.GenerateToken("user-1", "taro@example.com")
+	otherKey := NewJWTService(Config{SecretKey: "other-secret", Expires: time.Hour})
+	forgedToken, _ := otherKey.GenerateToken("user-1", "taro@example.com")
+	otherAudience := NewJWTService(Config{SecretKey: "test-secret", Expires: time.Hour, Issuer: "user-service", Audience: []string{"order-service"}})
+	audienceToken, _ := otherAudience.GenerateToken("user-1", "taro@example.com")
+
+	for _, tok := range []string{expiredToken, forgedToken, audienceToken, "not-a-token"} {
+		s.ValidateToken(tok)
+	}
+
+	if want := []string{IssueMethodPassword, IssueMethodRefresh}; !reflect.DeepEqual(observer.issued, want) {
+		t.Errorf("issued = %v, want %v", observer.issued, want)
+	}
+	want := []string{ValidationValid, ValidationExpired, ValidationInvalidSignature, ValidationInvalidAudience, ValidationMalformed}
+	if !reflect.DeepEqual(observer.validated, want) {
+		t.Errorf("validated = %v, want %v", observer.validated, want)
+	}
+}
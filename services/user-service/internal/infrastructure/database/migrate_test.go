@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// 埋め込んだマイグレーションがすべて読み込め、up と down が揃っていること
+func TestEmbeddedMigrations(t *testing.T) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := LoadMigrations(sub)
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	// バージョンは 1 から欠番なく続く
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: version = %d, want %d", m.Version, m.Name, m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has an empty up or down script", m.Version, m.Name)
+		}
+	}
+
+	// 読み込みで検出されない不要なファイルがないこと
+	entries, err := fs.ReadDir(sub, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2*len(migrations) {
+		t.Errorf("%d files for %d migrations", len(entries), len(migrations))
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	t.Run("sorted by numeric version", func(t *testing.T) {
+		migrations, err := LoadMigrations(fstest.MapFS{
+			"10_add_index.up.sql":        file("CREATE INDEX"),
+			"10_add_index.down.sql":      file("DROP INDEX"),
+			"2_add_column.up.sql":        file("ALTER TABLE ADD"),
+			"2_add_column.down.sql":      file("ALTER TABLE DROP"),
+			"0001_create_table.up.sql":   file("CREATE TABLE"),
+			"0001_create_table.down.sql": file("DROP TABLE"),
+		})
+		if err != nil {
+			t.Fatalf("LoadMigrations() error = %v", err)
+		}
+
+		var got []string
+		for _, m := range migrations {
+			got = append(got, m.Name+":"+m.Up+"/"+m.Down)
+		}
+		want := "create_table:CREATE TABLE/DROP TABLE,add_column:ALTER TABLE ADD/ALTER TABLE DROP,add_index:CREATE INDEX/DROP INDEX"
+		if strings.Join(got, ",") != want {
+			t.Errorf("migrations = %v", got)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"0001_create_table.up.sql": file("CREATE TABLE"),
+			},
+			wantErr: "must have both up and down files",
+		},
+		{
+			name: "duplicate version with different names",
+			fsys: fstest.MapFS{
+				"0001_create_table.up.sql":   file("CREATE TABLE"),
+				"0001_create_table.down.sql": file("DROP TABLE"),
+				"0001_other_table.up.sql":    file("CREATE TABLE"),
+				"0001_other_table.down.sql":  file("DROP TABLE"),
+			},
+			wantErr: "conflicting names for migration version 1",
+		},
+		{
+			name: "invalid file name",
+			fsys: fstest.MapFS{
+				"create_table.sql": file("CREATE TABLE"),
+			},
+			wantErr: "invalid migration file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadMigrations() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// USER_SERVICE_TEST_POSTGRES_DSN が設定されている場合のみ実行する
+// 他のテストのテーブルに影響しないよう専用のスキーマで実行する
+func TestMigrator_Postgres(t *testing.T) {
+	dsn := os.Getenv("USER_SERVICE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("USER_SERVICE_TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// search_path を保ったまま同じセッションを使うため接続を1つに制限する
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"DROP SCHEMA IF EXISTS migrator_test CASCADE",
+		"CREATE SCHEMA migrator_test",
+		"SET search_path TO migrator_test",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	t.Cleanup(func() { db.ExecContext(context.Background(), "DROP SCHEMA IF EXISTS migrator_test CASCADE") })
+
+	migrator := newMigrator(db, []Migration{
+		{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id int)", Down: "DROP TABLE a"},
+		{Version: 2, Name: "create_b", Up: "CREATE TABLE b (id int)", Down: "DROP TABLE b"},
+		{Version: 3, Name: "create_c", Up: "CREATE TABLE c (id int)", Down: "DROP TABLE c"},
+	})
+	versions := func(migrations []Migration) []int64 {
+		var v []int64
+		for _, m := range migrations {
+			v = append(v, m.Version)
+		}
+		return v
+	}
+	applied := func() []int64 {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+		var v []int64
+		for _, s := range statuses {
+			if s.Applied {
+				v = append(v, s.Version)
+			}
+		}
+		return v
+	}
+
+	// 1. 古い順に適用し、適用済みのものは再実行しない
+	up, err := migrator.Up(ctx)
+	if err != nil || !equalVersions(versions(up), 1, 2, 3) {
+		t.Fatalf("Up() = %v, %v", versions(up), err)
+	}
+	if up, err := migrator.Up(ctx); err != nil || len(up) != 0 {
+		t.Errorf("second Up() = %v, %v", versions(up), err)
+	}
+
+	// 2. 新しい順に取り消す
+	down, err := migrator.Down(ctx, 2)
+	if err != nil || !equalVersions(versions(down), 3, 2) {
+		t.Fatalf("Down(2) = %v, %v", versions(down), err)
+	}
+	if got := applied(); !equalVersions(got, 1) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+
+	// 3. 失敗したマイグレーションは記録せず、テーブルの変更も取り消される
+	failing := newMigrator(db, []Migration{
+		migrator.migrations[0],
+		{Version: 2, Name: "create_b", Up: "CREATE TABLE b (id int); SELECT no_such_function()", Down: "DROP TABLE b"},
+	})
+	if _, err := failing.Up(ctx); err == nil || !strings.Contains(err.Error(), "2_create_b") {
+		t.Errorf("Up() with a failing migration error = %v", err)
+	}
+	if got := applied(); !equalVersions(got, 1) {
+		t.Errorf("applied after failure = %v, want [1]", got)
+	}
+	if up, err := migrator.Up(ctx); err != nil || !equalVersions(versions(up), 2, 3) {
+		t.Errorf("Up() after failure = %v, %v", versions(up), err)
+	}
+}
+
+func equalVersions(got []int64, want ...int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
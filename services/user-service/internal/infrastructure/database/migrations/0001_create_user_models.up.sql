@@ -0,0 +1,13 @@
+-- 既存環境（AutoMigrateで作成済み）でも適用できるよう IF NOT EXISTS を使う
+CREATE TABLE IF NOT EXISTS user_models (
+    id         uuid PRIMARY KEY,
+    email      text        NOT NULL,
+    password   text        NOT NULL,
+    name       text        NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_models_email ON user_models (email);
+CREATE INDEX IF NOT EXISTS idx_user_models_deleted_at ON user_models (deleted_at);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS login_event_models (
+    id         uuid PRIMARY KEY,
+    user_id    uuid             NOT NULL REFERENCES user_models (id) ON DELETE CASCADE,
+    ip_address text             NOT NULL,
+    user_agent text             NOT NULL,
+    device_id  text             NOT NULL,
+    country    text,
+    city       text,
+    latitude   double precision,
+    longitude  double precision,
+    flags      text,
+    succeeded  boolean          NOT NULL,
+    created_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_event_models_user_created
+    ON login_event_models (user_id, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE user_models DROP COLUMN IF EXISTS version;
@@ -0,0 +1,2 @@
+-- 楽観的排他制御のためのバージョン列
+ALTER TABLE user_models ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_message_models (
+    id              uuid PRIMARY KEY,
+    event_type      text        NOT NULL,
+    aggregate_id    text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    occurred_at     timestamptz NOT NULL,
+    attempts        integer     NOT NULL DEFAULT 0,
+    last_error      text,
+    next_attempt_at timestamptz NOT NULL,
+    published_at    timestamptz,
+    created_at      timestamptz NOT NULL
+);
+
+-- 未送信メッセージの取得用
+CREATE INDEX IF NOT EXISTS idx_outbox_message_models_pending
+    ON outbox_message_models (next_attempt_at)
+    WHERE published_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_models_name_id;
+DROP INDEX IF EXISTS idx_user_models_created_at_id;
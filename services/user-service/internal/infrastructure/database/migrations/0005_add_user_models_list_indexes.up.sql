@@ -0,0 +1,3 @@
+-- ユーザー一覧のキーセットページング用（並び順キー + ID）
+CREATE INDEX IF NOT EXISTS idx_user_models_created_at_id ON user_models (created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_user_models_name_id ON user_models (name, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- 暗号化済みの行がある場合は、先に平文に戻しておくこと
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_models_email ON user_models (email);
+DROP INDEX IF EXISTS idx_user_models_email_index;
+ALTER TABLE user_models DROP COLUMN IF EXISTS email_index;
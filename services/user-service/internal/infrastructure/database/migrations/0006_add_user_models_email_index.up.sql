@@ -0,0 +1,11 @@
+-- 個人情報の暗号化のため、メールアドレスの検索と一意制約をブラインドインデックスの列に移す
+ALTER TABLE user_models ADD COLUMN IF NOT EXISTS email_index text;
+
+-- 暗号化前の行は平文のメールアドレスをインデックスとして使う（再暗号化ジョブでHMACに置き換わる）
+UPDATE user_models SET email_index = email WHERE email_index IS NULL;
+ALTER TABLE user_models ALTER COLUMN email_index SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_models_email_index ON user_models (email_index);
+
+-- 暗号文は値ごとに異なるため、email 列の一意制約は意味を持たない
+DROP INDEX IF EXISTS idx_user_models_email;
@@ -0,0 +1,2 @@
+-- メールやAPIのメッセージに使う言語（空の場合はリクエストの Accept-Language に従う）
+ALTER TABLE user_models ADD COLUMN IF NOT EXISTS locale text NOT NULL DEFAULT '';
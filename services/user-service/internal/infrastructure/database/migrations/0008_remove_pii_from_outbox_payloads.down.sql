@@ -0,0 +1 @@
+-- 取り除いた個人情報は復元できないため、何もしない
@@ -0,0 +1,5 @@
+-- イベントのスキーマ v2 に合わせ、アウトボックスに保存済みのイベントから個人情報（メールアドレス・氏名）を取り除く
+-- 送信済みの行も対象（保存されたままにしない）
+UPDATE outbox_message_models
+SET payload = payload - 'email' - 'name'
+WHERE event_type IN ('user.registered', 'user.updated', 'user.email_verified');
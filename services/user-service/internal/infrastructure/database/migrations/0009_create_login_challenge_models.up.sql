@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_challenge_models (
+    id             uuid PRIMARY KEY,
+    user_id        uuid        NOT NULL REFERENCES user_models (id) ON DELETE CASCADE,
+    login_event_id uuid        NOT NULL REFERENCES login_event_models (id) ON DELETE CASCADE,
+    code_hash      text        NOT NULL,
+    attempts       integer     NOT NULL DEFAULT 0,
+    expires_at     timestamptz NOT NULL,
+    created_at     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenge_models_user_id
+    ON login_challenge_models (user_id);
@@ -29,9 +29,9 @@ func NewPostgresDB(config Config) (*gorm.DB, error) {
 		config.SSLMode,
 	)
 
-	// GORMのロガー
+	// GORMのロガー（SQLに個人情報が含まれるため、既定ではエラーと遅いクエリのみ出力する）
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(logger.Warn),
 	}
 
 	// データベース接続
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteへの接続（テスト・ローカル実行用）
+// path に ":memory:" を指定するとプロセス内のインメモリデータベースになる
+func NewSQLiteDB(path string) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", path)
+	if path == ":memory:" {
+		dsn = "file::memory:?_foreign_keys=on"
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLiteは同時に1つの書き込みしかできず、インメモリDBは接続ごとに別になるため接続を1つに制限する
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db, nil
+}
@@ -0,0 +1,174 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func newTestKMS(t *testing.T, current string, keys ...string) *LocalKMS {
+	t.Helper()
+	file := keyFile{CurrentKeyID: current, Keys: map[string]string{}, BlindIndexKey: testKey('i')}
+	for i, id := range keys {
+		file.Keys[id] = testKey(byte('a' + i))
+	}
+	kms, err := newLocalKMS(file)
+	if err != nil {
+		t.Fatalf("newLocalKMS() error = %v", err)
+	}
+	return kms
+}
+
+func TestFieldEncryptor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kms := newTestKMS(t, "k1", "k1")
+	e := NewFieldEncryptor(kms, kms.BlindIndexKey())
+
+	ciphertext, err := e.Encrypt(ctx, "taro@example.com", "user_models.email:1")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(ciphertext) || strings.Contains(ciphertext, "taro") {
+		t.Errorf("Encrypt() = %q", ciphertext)
+	}
+
+	again, _ := e.Encrypt(ctx, "taro@example.com", "user_models.email:1")
+	if again == ciphertext {
+		t.Error("Encrypt() is deterministic, want a fresh data key and nonce per value")
+	}
+
+	plaintext, err := e.Decrypt(ctx, ciphertext, "user_models.email:1")
+	if err != nil || plaintext != "taro@example.com" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+
+	tests := []struct {
+		name           string
+		ciphertext     string
+		additionalData string
+	}{
+		{name: "different column", ciphertext: ciphertext, additionalData: "user_models.name:1"},
+		{name: "different row", ciphertext: ciphertext, additionalData: "user_models.email:2"},
+		{name: "tampered ciphertext", ciphertext: ciphertext[:len(ciphertext)-2] + "AA", additionalData: "user_models.email:1"},
+		{name: "malformed envelope", ciphertext: envelopePrefix + "k1:abc", additionalData: "user_models.email:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := e.Decrypt(ctx, tt.ciphertext, tt.additionalData); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("Decrypt() error = %v, want %v", err, ErrDecryptionFailed)
+			}
+		})
+	}
+
+	t.Run("plaintext passes through", func(t *testing.T) {
+		plaintext, err := e.Decrypt(ctx, "legacy@example.com", "user_models.email:1")
+		if err != nil || plaintext != "legacy@example.com" {
+			t.Errorf("Decrypt() = %q, %v", plaintext, err)
+		}
+	})
+}
+
+func TestFieldEncryptor_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKMS := newTestKMS(t, "k1", "k1")
+	oldEncryptor := NewFieldEncryptor(oldKMS, oldKMS.BlindIndexKey())
+	ciphertext, err := oldEncryptor.Encrypt(ctx, "Taro", "user_models.name:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新しい鍵を追加して切り替えた後も古い鍵の値を復号できる
+	rotated := newTestKMS(t, "k2", "k1", "k2")
+	e := NewFieldEncryptor(rotated, rotated.BlindIndexKey())
+	if plaintext, err := e.Decrypt(ctx, ciphertext, "user_models.name:1"); err != nil || plaintext != "Taro" {
+		t.Fatalf("Decrypt() after rotation = %q, %v", plaintext, err)
+	}
+	if !e.NeedsReencryption(ciphertext) {
+		t.Error("NeedsReencryption() = false for a value under the old key")
+	}
+	if !e.NeedsReencryption("plaintext") {
+		t.Error("NeedsReencryption() = false for a plaintext value")
+	}
+
+	reencrypted, _ := e.Encrypt(ctx, "Taro", "user_models.name:1")
+	if e.NeedsReencryption(reencrypted) {
+		t.Error("NeedsReencryption() = true for a value under the current key")
+	}
+
+	// 古い鍵を削除した後は復号できない
+	retired := newTestKMS(t, "k2", "k2")
+	if _, err := NewFieldEncryptor(retired, nil).Decrypt(ctx, ciphertext, "user_models.name:1"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestFieldEncryptor_BlindIndex(t *testing.T) {
+	e := NewFieldEncryptor(nil, []byte("index-key"))
+
+	if e.BlindIndex("taro@example.com") != e.BlindIndex("taro@example.com") {
+		t.Error("BlindIndex() is not deterministic")
+	}
+	if e.BlindIndex("taro@example.com") == e.BlindIndex("hanako@example.com") {
+		t.Error("BlindIndex() collides for different values")
+	}
+	if NewFieldEncryptor(nil, []byte("other-key")).BlindIndex("taro@example.com") == e.BlindIndex("taro@example.com") {
+		t.Error("BlindIndex() does not depend on the key")
+	}
+}
+
+func TestNewLocalKMS(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: `{"current_key_id":"k1","keys":{"k1":"` + testKey('a') + `"},"blind_index_key":"` + testKey('i') + `"}`,
+		},
+		{
+			name:    "current key missing",
+			content: `{"current_key_id":"k2","keys":{"k1":"` + testKey('a') + `"},"blind_index_key":"` + testKey('i') + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "short key",
+			content: `{"current_key_id":"k1","keys":{"k1":"c2hvcnQ="},"blind_index_key":"` + testKey('i') + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "key id with separator",
+			content: `{"current_key_id":"k:1","keys":{"k:1":"` + testKey('a') + `"},"blind_index_key":"` + testKey('i') + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing blind index key",
+			content: `{"current_key_id":"k1","keys":{"k1":"` + testKey('a') + `"}}`,
+			wantErr: true,
+		},
+		{name: "malformed json", content: `{`, wantErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewLocalKMS(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLocalKMS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
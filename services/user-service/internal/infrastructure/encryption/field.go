@@ -0,0 +1,124 @@
+package encryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 暗号化済みの値の接頭辞
+// 形式: "enc:v1:<鍵ID>:<暗号化したデータ鍵>:<暗号文>"（データ鍵と暗号文は base64url）
+const envelopePrefix = "enc:v1:"
+
+// 列単位のエンベロープ暗号化
+// 値ごとにデータ鍵を生成してAES-256-GCMで暗号化し、データ鍵はKMSの鍵で暗号化して値と一緒に保存する
+type FieldEncryptor struct {
+	kms           KMS
+	blindIndexKey []byte
+}
+
+// 列の暗号化を行う構造体を作成する関数
+func NewFieldEncryptor(kms KMS, blindIndexKey []byte) *FieldEncryptor {
+	return &FieldEncryptor{
+		kms:           kms,
+		blindIndexKey: blindIndexKey,
+	}
+}
+
+// 値の暗号化
+// additionalData には列名やレコードIDを渡し、暗号文を別の列・行にコピーしても復号できないようにする
+func (e *FieldEncryptor) Encrypt(ctx context.Context, plaintext, additionalData string) (string, error) {
+	// 1. データ鍵の生成
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	// 2. データ鍵で値を暗号化
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+
+	// 3. KMSでデータ鍵を暗号化
+	keyID, wrapped, err := e.kms.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	return envelopePrefix + keyID + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// 値の復号
+// 暗号化されていない値（暗号化導入前のデータ）はそのまま返す
+func (e *FieldEncryptor) Decrypt(ctx context.Context, value, additionalData string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	keyID, wrapped, sealed, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := e.kms.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// 現在の鍵で暗号化し直す必要があるか（未暗号化の値も対象）
+func (e *FieldEncryptor) NeedsReencryption(value string) bool {
+	if !IsEncrypted(value) {
+		return true
+	}
+	keyID, _, _, err := parseEnvelope(value)
+	return err != nil || keyID != e.kms.CurrentKeyID()
+}
+
+// 完全一致の検索に使うブラインドインデックス（HMAC-SHA256）
+// 同じ値からは常に同じインデックスが得られるが、インデックスから値は復元できない
+func (e *FieldEncryptor) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, e.blindIndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 暗号化済みの値か
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+func parseEnvelope(value string) (keyID string, wrapped, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrDecryptionFailed
+	}
+	if wrapped, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, ErrDecryptionFailed
+	}
+	if sealed, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrDecryptionFailed
+	}
+	return parts[0], wrapped, sealed, nil
+}
@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnknownKey       = errors.New("unknown encryption key")
+	ErrInvalidKeyFile   = errors.New("invalid key file")
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+// データ鍵を暗号化する鍵管理サービスのインターフェース
+// 外部のKMS（AWS KMS など）に差し替えられるよう、鍵そのものは外に出さない
+type KMS interface {
+	// 現在の鍵ID
+	CurrentKeyID() string
+	// 現在の鍵でデータ鍵を暗号化し、使った鍵IDを返す
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// 指定した鍵でデータ鍵を復号する
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// 鍵ファイルの形式
+//
+//	{
+//	  "current_key_id": "2024-06",
+//	  "keys": {"2024-01": "<base64>", "2024-06": "<base64>"},
+//	  "blind_index_key": "<base64>"
+//	}
+//
+// 鍵はいずれも32バイト。ローテーション時は新しい鍵を追加して current_key_id を切り替え、
+// 再暗号化ジョブの完了後に古い鍵を削除する
+type keyFile struct {
+	CurrentKeyID  string            `json:"current_key_id"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blind_index_key"`
+}
+
+// ローカルの鍵ファイルを使うKMS（開発環境・単一ノード向け）
+type LocalKMS struct {
+	currentKeyID string
+	keys         map[string]cipher.AEAD
+	// ブラインドインデックスの鍵（ローテーションの対象外）
+	blindIndexKey []byte
+}
+
+// 鍵ファイルからKMSを作成する関数
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+	return newLocalKMS(file)
+}
+
+func newLocalKMS(file keyFile) (*LocalKMS, error) {
+	if _, ok := file.Keys[file.CurrentKeyID]; !ok {
+		return nil, fmt.Errorf("%w: current key %q is not defined", ErrInvalidKeyFile, file.CurrentKeyID)
+	}
+
+	kms := &LocalKMS{
+		currentKeyID: file.CurrentKeyID,
+		keys:         make(map[string]cipher.AEAD, len(file.Keys)),
+	}
+	for id, encoded := range file.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: key id %q must be non-empty and must not contain ':'", ErrInvalidKeyFile, id)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidKeyFile, id, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		kms.keys[id] = aead
+	}
+
+	indexKey, err := decodeKey(file.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: blind_index_key: %v", ErrInvalidKeyFile, err)
+	}
+	kms.blindIndexKey = indexKey
+
+	return kms, nil
+}
+
+func (k *LocalKMS) CurrentKeyID() string {
+	return k.currentKeyID
+}
+
+// ブラインドインデックスの鍵
+func (k *LocalKMS) BlindIndexKey() []byte {
+	return k.blindIndexKey
+}
+
+func (k *LocalKMS) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(k.keys[k.currentKeyID], dataKey, []byte(k.currentKeyID))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.currentKeyID, wrapped, nil
+}
+
+func (k *LocalKMS) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// AES-GCMで暗号化する（結果の先頭にノンスを付ける）
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// seal で暗号化したデータを復号する
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,116 @@
+package geo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+)
+
+// IPアドレスから大まかな位置情報を引くインターフェース
+type Locator interface {
+	// 位置情報が見つからない場合は nil を返す
+	Lookup(ip string) (*domain.GeoLocation, error)
+}
+
+// 位置情報を返さないロケーター（データベースファイル未設定時）
+type noopLocator struct{}
+
+func NewNoopLocator() Locator {
+	return noopLocator{}
+}
+
+func (noopLocator) Lookup(string) (*domain.GeoLocation, error) {
+	return nil, nil
+}
+
+type networkEntry struct {
+	prefix   netip.Prefix
+	location domain.GeoLocation
+}
+
+// ローカルのCSVファイルを使うロケーター
+// 各行は "network,country,city,latitude,longitude" の形式（例: 203.0.113.0/24,JP,Tokyo,35.68,139.69）
+type fileLocator struct {
+	entries []networkEntry
+}
+
+// CSVファイルからロケーターを作成する関数
+func NewFileLocator(path string) (Locator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geo database: %w", err)
+	}
+	defer f.Close()
+
+	return newFileLocator(f)
+}
+
+func newFileLocator(r io.Reader) (*fileLocator, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 5
+
+	var entries []networkEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read geo database: %w", err)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", record[0], err)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude for %s: %w", record[0], err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(record[4]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude for %s: %w", record[0], err)
+		}
+
+		entries = append(entries, networkEntry{
+			prefix: prefix.Masked(),
+			location: domain.GeoLocation{
+				Country:   strings.TrimSpace(record[1]),
+				City:      strings.TrimSpace(record[2]),
+				Latitude:  lat,
+				Longitude: lon,
+			},
+		})
+	}
+
+	// 最長一致で検索するため、プレフィックス長の長い順に並べる
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].prefix.Bits() > entries[j].prefix.Bits()
+	})
+
+	return &fileLocator{entries: entries}, nil
+}
+
+func (l *fileLocator) Lookup(ip string) (*domain.GeoLocation, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip address %q: %w", ip, err)
+	}
+	addr = addr.Unmap()
+
+	for _, entry := range l.entries {
+		if entry.prefix.Contains(addr) {
+			location := entry.location
+			return &location, nil
+		}
+	}
+	return nil, nil
+}
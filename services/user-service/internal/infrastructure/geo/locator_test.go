@@ -0,0 +1,70 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDatabase = `# network,country,city,latitude,longitude
+203.0.113.0/24,JP,Tokyo,35.68,139.69
+203.0.113.128/25,JP,Osaka,34.69,135.50
+2001:db8::/32,US,New York,40.71,-74.01
+`
+
+func TestFileLocator_Lookup(t *testing.T) {
+	locator, err := newFileLocator(strings.NewReader(testDatabase))
+	if err != nil {
+		t.Fatalf("newFileLocator() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		wantCity string
+		wantErr  bool
+	}{
+		{name: "ipv4 match", ip: "203.0.113.10", wantCity: "Tokyo"},
+		{name: "longest prefix wins", ip: "203.0.113.200", wantCity: "Osaka"},
+		{name: "ipv4-mapped ipv6", ip: "::ffff:203.0.113.10", wantCity: "Tokyo"},
+		{name: "ipv6 match", ip: "2001:db8::1", wantCity: "New York"},
+		{name: "no match", ip: "192.0.2.1"},
+		{name: "invalid address", ip: "not-an-ip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := locator.Lookup(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantCity == "" {
+				if location != nil {
+					t.Errorf("Lookup() = %+v, want nil", location)
+				}
+				return
+			}
+			if location == nil || location.City != tt.wantCity {
+				t.Errorf("Lookup() = %+v, want city %s", location, tt.wantCity)
+			}
+		})
+	}
+}
+
+func TestNewFileLocator_InvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid network", data: "not-a-network,JP,Tokyo,35.68,139.69\n"},
+		{name: "invalid latitude", data: "203.0.113.0/24,JP,Tokyo,north,139.69\n"},
+		{name: "missing fields", data: "203.0.113.0/24,JP,Tokyo\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newFileLocator(strings.NewReader(tt.data)); err == nil {
+				t.Error("newFileLocator() error = nil, want error")
+			}
+		})
+	}
+}
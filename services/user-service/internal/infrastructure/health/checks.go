@@ -0,0 +1,37 @@
+package health
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// データベースの確認（コネクションプールから接続して ping する）
+func DatabaseChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// 状態確認ができる接続（メッセージブローカーなど）
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping による確認
+func PingChecker(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// Redis の確認（アプリケーションと同じクライアントで PING を送る）
+// 接続先・DB番号・認証・TLS の設定はクライアントの設定をそのまま使う
+func RedisChecker(client redis.UniversalClient) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 状態
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// 依存先の状態確認のインターフェース
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// 関数を Checker として扱う
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// 登録された確認
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+}
+
+// 確認の結果
+type CheckResult struct {
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// すべての確認の結果
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// 停止処理中であることを示す確認の名前
+const CheckShutdown = "shutdown"
+
+// 依存先の確認を登録するレジストリ
+type Registry struct {
+	mu             sync.RWMutex
+	checks         []check
+	defaultTimeout time.Duration
+	// 停止処理中は依存先に関係なく準備未完了とする
+	draining atomic.Bool
+}
+
+// レジストリの作成（timeout は確認ごとの既定のタイムアウト）
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{defaultTimeout: timeout}
+}
+
+// 確認の登録（timeout が0の場合は既定のタイムアウトを使う）
+func (r *Registry) Register(name string, checker Checker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = r.defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, checker: checker, timeout: timeout})
+}
+
+// 登録された確認の名前
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for _, c := range r.checks {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 停止処理の開始（以降の Run は常に down を返す）
+func (r *Registry) SetDraining() {
+	r.draining.Store(true)
+}
+
+// すべての確認を並行して実行する（1つでも失敗した場合は down）
+func (r *Registry) Run(ctx context.Context) Report {
+	if r.draining.Load() {
+		return Report{
+			Status: StatusDown,
+			Checks: map[string]CheckResult{
+				CheckShutdown: {Status: StatusDown, Error: "server is shutting down"},
+			},
+		}
+	}
+
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// タイムアウト付きで確認を実行する
+// Checker がコンテキストを無視して戻らない場合もタイムアウトで打ち切る
+func runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:     StatusUp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
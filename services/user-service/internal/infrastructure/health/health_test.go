@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("ok", CheckerFunc(func(context.Context) error { return nil }), 0)
+
+	report := registry.Run(context.Background())
+	if report.Status != StatusUp || report.Checks["ok"].Status != StatusUp {
+		t.Errorf("Run() = %+v", report)
+	}
+
+	registry.Register("failing", CheckerFunc(func(context.Context) error { return errors.New("boom") }), 0)
+
+	report = registry.Run(context.Background())
+	if report.Status != StatusDown {
+		t.Errorf("Status = %q, want down", report.Status)
+	}
+	if got := report.Checks["failing"]; got.Status != StatusDown || got.Error != "boom" {
+		t.Errorf("Checks[failing] = %+v", got)
+	}
+	if got := report.Checks["ok"]; got.Status != StatusUp {
+		t.Errorf("Checks[ok] = %+v", got)
+	}
+	if names := registry.Names(); strings.Join(names, ",") != "failing,ok" {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestRegistry_Draining(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("ok", CheckerFunc(func(context.Context) error { return nil }), 0)
+
+	registry.SetDraining()
+
+	report := registry.Run(context.Background())
+	if report.Status != StatusDown || report.Checks[CheckShutdown].Status != StatusDown {
+		t.Errorf("Run() = %+v", report)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	// コンテキストを無視する確認もタイムアウトで打ち切る
+	block := make(chan struct{})
+	defer close(block)
+	registry.Register("stuck", CheckerFunc(func(context.Context) error {
+		<-block
+		return nil
+	}), 20*time.Millisecond)
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Run() took %s", elapsed)
+	}
+	if got := report.Checks["stuck"]; got.Status != StatusDown || !strings.Contains(got.Error, "deadline exceeded") {
+		t.Errorf("Checks[stuck] = %+v", got)
+	}
+}
+
+func TestRedisChecker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	if err := RedisChecker(client).Check(ctx); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	server.RequireAuth("secret")
+	authed := redis.NewClient(&redis.Options{Addr: server.Addr(), Password: "secret"})
+	t.Cleanup(func() { authed.Close() })
+	if err := RedisChecker(authed).Check(ctx); err != nil {
+		t.Errorf("Check() with password error = %v", err)
+	}
+	unauthed := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { unauthed.Close() })
+	if err := RedisChecker(unauthed).Check(ctx); err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Errorf("Check() without password error = %v", err)
+	}
+}
+
+func TestRedisChecker_Unreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	server.Close()
+
+	if err := RedisChecker(client).Check(context.Background()); err == nil {
+		t.Error("Check() should fail when redis is unreachable")
+	}
+}
@@ -0,0 +1,120 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/logging"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// リクエストIDのメタデータのキー（gRPC のメタデータは小文字）
+const MetadataRequestID = "x-request-id"
+
+// 呼び出しごとの情報（内側のインターセプターからアクセスログに渡す）
+type callInfo struct {
+	userID string
+}
+
+type callInfoKey struct{}
+
+// 認証したユーザーをアクセスログに記録する
+func setUserID(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(callInfoKey{}).(*callInfo); ok {
+		info.userID = userID
+	}
+}
+
+// リクエストIDの付与（単項呼び出し）
+// クライアントやゲートウェイから受け取った x-request-id を引き継ぎ、ない場合は生成する
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, id := requestID(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(MetadataRequestID, id))
+		return handler(ctx, req)
+	}
+}
+
+// リクエストIDの付与（ストリーム）
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := requestID(ss.Context())
+		ss.SetHeader(metadata.Pairs(MetadataRequestID, id))
+		return handler(srv, withContext(ss, ctx))
+	}
+}
+
+func requestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataRequestID); len(values) > 0 {
+		id = values[0]
+	}
+	if !logging.ValidRequestID(id) {
+		id = uuid.New().String()
+	}
+	return logging.WithRequestID(ctx, id), id
+}
+
+// アクセスログの出力（単項呼び出し）
+func UnaryAccessLog(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		call := &callInfo{}
+		res, err := handler(context.WithValue(ctx, callInfoKey{}, call), req)
+		logCall(ctx, logger, info.FullMethod, false, call, err, start)
+		return res, err
+	}
+}
+
+// アクセスログの出力（ストリーム）
+func StreamAccessLog(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		call := &callInfo{}
+		ctx := ss.Context()
+		err := handler(srv, withContext(ss, context.WithValue(ctx, callInfoKey{}, call)))
+		logCall(ctx, logger, info.FullMethod, true, call, err, start)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, stream bool, call *callInfo, err error, start time.Time) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if stream {
+		attrs = append(attrs, slog.Bool("stream", true))
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if call.userID != "" {
+		attrs = append(attrs, slog.String("user_id", call.userID))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+
+	logger.LogAttrs(ctx, logLevel(code), "grpc request", attrs...)
+}
+
+// ステータスのコードに応じたログのレベル（HTTP の 5xx・4xx に相当するものを分ける）
+func logLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
@@ -0,0 +1,120 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// アクセストークンのメタデータのキー（HTTP の Authorization ヘッダーに相当する）
+const MetadataAuthorization = "authorization"
+
+// メソッドごとの認可の設定
+type MethodPolicy struct {
+	// 認証なしで呼び出せる（ヘルスチェックなど）
+	Public bool
+	// すべて必要なスコープ
+	Scopes []string
+}
+
+// JWT による認証とメソッドごとの認可
+type AuthInterceptor struct {
+	jwtService *auth.JWTService
+	policies   map[string]MethodPolicy
+}
+
+// インターセプターの作成
+// policies のキーはメソッドの完全名（/user.v1.UserService/GetUser）かサービス名（/grpc.health.v1.Health/）
+// 設定のないメソッドは呼び出せない
+func NewAuthInterceptor(jwtService *auth.JWTService, policies map[string]MethodPolicy) *AuthInterceptor {
+	return &AuthInterceptor{
+		jwtService: jwtService,
+		policies:   policies,
+	}
+}
+
+func (i *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, withContext(ss, ctx))
+	}
+}
+
+func (i *AuthInterceptor) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	// 1. メソッドの設定の取得（設定のないメソッドは拒否する）
+	policy, ok := i.policy(fullMethod)
+	if !ok {
+		return nil, StatusError(codes.PermissionDenied, apierror.CodeMethodNotAllowed, "Method not allowed")
+	}
+	if policy.Public {
+		return ctx, nil
+	}
+
+	// 2. Bearer token の取得
+	values := metadata.ValueFromIncomingContext(ctx, MetadataAuthorization)
+	if len(values) == 0 || values[0] == "" {
+		return nil, StatusError(codes.Unauthenticated, apierror.CodeUnauthorized, "Authorization metadata is required")
+	}
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, StatusError(codes.Unauthenticated, apierror.CodeInvalidToken, "Invalid authorization format")
+	}
+
+	// 3. トークンの検証
+	claims, err := i.jwtService.ValidateToken(parts[1])
+	if err != nil {
+		return nil, StatusError(codes.Unauthenticated, apierror.CodeInvalidToken, "Invalid or expired token")
+	}
+	setUserID(ctx, claims.UserID)
+
+	// 4. スコープの確認
+	if !claims.HasScopes(policy.Scopes...) {
+		return nil, StatusError(codes.PermissionDenied, apierror.CodeInsufficientScope, "Insufficient scope")
+	}
+
+	// 5. クレームと希望する言語をコンテキストに設定
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	if i18n.Supported(claims.Locale) {
+		ctx = i18n.WithLocale(ctx, claims.Locale)
+	}
+	return ctx, nil
+}
+
+// メソッドの完全名、サービス名の順に設定を探す
+func (i *AuthInterceptor) policy(fullMethod string) (MethodPolicy, bool) {
+	if policy, ok := i.policies[fullMethod]; ok {
+		return policy, true
+	}
+	if slash := strings.LastIndex(fullMethod, "/"); slash > 0 {
+		policy, ok := i.policies[fullMethod[:slash+1]]
+		return policy, ok
+	}
+	return MethodPolicy{}, false
+}
+
+type claimsKey struct{}
+
+// 認証したトークンのクレーム（公開メソッドでは設定されない）
+func ClaimsFromContext(ctx context.Context) (*auth.JWTClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*auth.JWTClaims)
+	return claims, ok
+}
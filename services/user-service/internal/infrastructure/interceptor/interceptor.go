@@ -0,0 +1,43 @@
+// gRPC サーバーのインターセプター（HTTP の middleware パッケージに相当する）
+//
+// main.go では次の順に連結する:
+//
+//	RequestID → メトリクス → AccessLog → Recovery → Auth
+//
+// Recovery を AccessLog の内側に置くことで、パニックも Internal としてログとメトリクスに残る
+package interceptor
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfo の domain（エラーコードの名前空間）
+const ErrorDomain = "user-service"
+
+// エラーコード（REST API と同じ値）を ErrorInfo の reason に付けたステータスを作成する
+func StatusError(code codes.Code, reason, msg string) error {
+	st := status.New(code, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// コンテキストを差し替えたストリーム
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func withContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
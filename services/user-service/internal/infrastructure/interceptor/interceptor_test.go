@@ -0,0 +1,285 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// テスト用のストリーム（コンテキストと送信したヘッダーのみを扱う）
+type fakeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func (s *fakeStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func newJWTService() *auth.JWTService {
+	return auth.NewJWTService(auth.Config{SecretKey: "test-secret", Expires: time.Hour})
+}
+
+// ステータスのコードと ErrorInfo の reason を検証する
+func assertStatus(t *testing.T, err error, wantCode codes.Code, wantReason string) {
+	t.Helper()
+	st, _ := status.FromError(err)
+	if st.Code() != wantCode {
+		t.Fatalf("code = %s, want %s (err = %v)", st.Code(), wantCode, err)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.Reason != wantReason || info.Domain != ErrorDomain {
+				t.Errorf("ErrorInfo = %s/%s, want %s/%s", info.Domain, info.Reason, ErrorDomain, wantReason)
+			}
+			return
+		}
+	}
+	t.Errorf("status has no ErrorInfo: %v", st.Details())
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	jwtService := newJWTService()
+	interceptor := NewAuthInterceptor(jwtService, map[string]MethodPolicy{
+		"/user.v1.UserService/GetUser":       {Scopes: []string{auth.ScopeUsersRead}},
+		"/user.v1.UserService/ValidateToken": {Public: true},
+		"/grpc.health.v1.Health/":            {Public: true},
+	})
+
+	reader, err := jwtService.IssueToken(auth.TokenParams{UserID: "user-1", Email: "taro@example.com", Scopes: []string{auth.ScopeUsersRead}, Locale: i18n.Japanese})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := jwtService.GenerateToken("user-2", "hanako@example.com", auth.ScopeProfileWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantCode      codes.Code
+		wantReason    string
+		wantUserID    string
+	}{
+		{name: "authorized", method: "/user.v1.UserService/GetUser", authorization: "Bearer " + reader, wantUserID: "user-1"},
+		{name: "public method", method: "/user.v1.UserService/ValidateToken"},
+		{name: "public service", method: "/grpc.health.v1.Health/Check"},
+		{name: "missing token", method: "/user.v1.UserService/GetUser", wantCode: codes.Unauthenticated, wantReason: apierror.CodeUnauthorized},
+		{name: "invalid format", method: "/user.v1.UserService/GetUser", authorization: "Token " + reader, wantCode: codes.Unauthenticated, wantReason: apierror.CodeInvalidToken},
+		{name: "invalid token", method: "/user.v1.UserService/GetUser", authorization: "Bearer invalid", wantCode: codes.Unauthenticated, wantReason: apierror.CodeInvalidToken},
+		{name: "insufficient scope", method: "/user.v1.UserService/GetUser", authorization: "Bearer " + writer, wantCode: codes.PermissionDenied, wantReason: apierror.CodeInsufficientScope},
+		// 設定のないメソッドは認証していても拒否する
+		{name: "unlisted method", method: "/user.v1.UserService/DeleteUser", authorization: "Bearer " + reader, wantCode: codes.PermissionDenied, wantReason: apierror.CodeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataAuthorization, tt.authorization))
+			}
+
+			var called bool
+			var claims *auth.JWTClaims
+			var locale string
+			_, err := interceptor.Unary()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req any) (any, error) {
+				called = true
+				claims, _ = ClaimsFromContext(ctx)
+				locale = i18n.LocaleFromContext(ctx)
+				return nil, nil
+			})
+
+			if tt.wantCode != codes.OK {
+				assertStatus(t, err, tt.wantCode, tt.wantReason)
+				if called {
+					t.Error("handler was called")
+				}
+				return
+			}
+			if err != nil || !called {
+				t.Fatalf("error = %v, called = %v", err, called)
+			}
+			if tt.wantUserID == "" {
+				if claims != nil {
+					t.Errorf("claims = %+v, want none for public methods", claims)
+				}
+				return
+			}
+			if claims == nil || claims.UserID != tt.wantUserID {
+				t.Errorf("claims = %+v", claims)
+			}
+			// ユーザーが希望する言語をコンテキストに設定する
+			if locale != i18n.Japanese {
+				t.Errorf("locale = %q", locale)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptor_Stream(t *testing.T) {
+	jwtService := newJWTService()
+	interceptor := NewAuthInterceptor(jwtService, map[string]MethodPolicy{
+		"/grpc.reflection.v1.ServerReflection/": {Scopes: []string{auth.ScopeUsersRead}},
+	})
+	token, err := jwtService.GenerateToken("user-1", "taro@example.com", auth.ScopeUsersRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}
+
+	var userID string
+	stream := &fakeStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataAuthorization, "Bearer "+token))}
+	err = interceptor.Stream()(nil, stream, info, func(_ any, ss grpc.ServerStream) error {
+		if claims, ok := ClaimsFromContext(ss.Context()); ok {
+			userID = claims.UserID
+		}
+		return nil
+	})
+	if err != nil || userID != "user-1" {
+		t.Errorf("error = %v, user = %q", err, userID)
+	}
+
+	err = interceptor.Stream()(nil, &fakeStream{ctx: context.Background()}, info, func(any, grpc.ServerStream) error {
+		t.Error("handler was called")
+		return nil
+	})
+	assertStatus(t, err, codes.Unauthenticated, apierror.CodeUnauthorized)
+}
+
+func TestRecovery(t *testing.T) {
+	unaryInfo := &grpc.UnaryServerInfo{FullMethod: "/user.v1.UserService/GetUser"}
+	_, err := UnaryRecovery()(context.Background(), nil, unaryInfo, func(context.Context, any) (any, error) {
+		panic("nil map")
+	})
+	assertStatus(t, err, codes.Internal, apierror.CodeInternal)
+	// パニックの内容は呼び出し元に返さない
+	if strings.Contains(status.Convert(err).Message(), "nil map") {
+		t.Errorf("message leaks panic value: %v", err)
+	}
+
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}
+	err = StreamRecovery()(nil, &fakeStream{ctx: context.Background()}, streamInfo, func(any, grpc.ServerStream) error {
+		panic("closed channel")
+	})
+	assertStatus(t, err, codes.Internal, apierror.CodeInternal)
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Config{Level: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		requestID string
+		err       error
+		userID    string
+		wantLevel string
+		wantCode  string
+		generated bool
+	}{
+		{name: "propagated", requestID: "gateway-42", userID: "user-1", wantLevel: "INFO", wantCode: "OK"},
+		// ログに改行などを混入させない
+		{name: "rejected", requestID: "bad\nvalue", wantLevel: "INFO", wantCode: "OK", generated: true},
+		{name: "client error", err: status.Error(codes.NotFound, "user not found"), wantLevel: "WARN", wantCode: "NotFound", generated: true},
+		{name: "server error", err: status.Error(codes.Internal, "boom"), wantLevel: "ERROR", wantCode: "Internal", generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataRequestID, tt.requestID))
+			}
+			stream := &fakeStream{ctx: ctx}
+
+			// ストリームの経路で RequestID → AccessLog → ハンドラーの順に呼び出す
+			var seen string
+			info := &grpc.StreamServerInfo{FullMethod: "/user.v1.UserService/GetUser"}
+			err := StreamRequestID()(nil, stream, info, func(srv any, ss grpc.ServerStream) error {
+				return StreamAccessLog(logger)(srv, ss, info, func(_ any, ss grpc.ServerStream) error {
+					seen = logging.RequestIDFromContext(ss.Context())
+					if tt.userID != "" {
+						setUserID(ss.Context(), tt.userID)
+					}
+					return tt.err
+				})
+			})
+			if err != tt.err {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+
+			if tt.generated {
+				if seen == "" || seen == tt.requestID {
+					t.Errorf("request id = %q, want a generated one", seen)
+				}
+			} else if seen != tt.requestID {
+				t.Errorf("request id = %q, want %q", seen, tt.requestID)
+			}
+			// 応答のヘッダーでもリクエストIDを返す
+			if got := stream.header.Get(MetadataRequestID); len(got) != 1 || got[0] != seen {
+				t.Errorf("header = %v, want %q", got, seen)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid log %q: %v", buf.String(), err)
+			}
+			if entry["level"] != tt.wantLevel || entry["code"] != tt.wantCode || entry["method"] != info.FullMethod {
+				t.Errorf("log = %v", entry)
+			}
+			if entry[logging.KeyRequestID] != seen || entry["stream"] != true {
+				t.Errorf("log = %v", entry)
+			}
+			if tt.userID != "" && entry["user_id"] != tt.userID {
+				t.Errorf("user_id = %v, want %q", entry["user_id"], tt.userID)
+			}
+		})
+	}
+}
+
+func TestUnaryRequestIDAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Config{Level: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataRequestID, "gateway-42"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.v1.UserService/GetUser"}
+	var seen string
+	_, err = UnaryRequestID()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		return UnaryAccessLog(logger)(ctx, req, info, func(ctx context.Context, _ any) (any, error) {
+			seen = logging.RequestIDFromContext(ctx)
+			return "ok", nil
+		})
+	})
+	if err != nil || seen != "gateway-42" {
+		t.Fatalf("error = %v, request id = %q", err, seen)
+	}
+	if !strings.Contains(buf.String(), `"request_id":"gateway-42"`) || strings.Contains(buf.String(), `"stream"`) {
+		t.Errorf("log = %s", buf.String())
+	}
+}
@@ -0,0 +1,41 @@
+package interceptor
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// パニックからの復帰（単項呼び出し）
+// 呼び出し元には詳細を返さず Internal とし、スタックトレースはログに出力する
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// パニックからの復帰（ストリーム）
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, r any) error {
+	slog.ErrorContext(ctx, "grpc panic recovered", "method", method, "panic", r, "stack", string(debug.Stack()))
+	return StatusError(codes.Internal, apierror.CodeInternal, "Internal server error")
+}
@@ -0,0 +1,225 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 停止処理の設定
+type Config struct {
+	// 停止の開始から、処理中のリクエスト・バックグラウンド処理・接続の終了を待つ最大時間
+	GracePeriod time.Duration
+	// 準備完了を取り下げてから新しい接続の受付を止めるまでの待ち時間
+	// （ロードバランサーが振り分け先から外すまでの猶予）
+	DrainDelay time.Duration
+}
+
+// 停止する部品
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// HTTPサーバーと並行して動かすサーバー（gRPC など）
+type additionalServer struct {
+	name     string
+	listener net.Listener
+	serve    func(net.Listener) error
+	stop     func(ctx context.Context) error
+}
+
+// バックグラウンド処理
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// HTTPサーバーと周辺の部品の起動・停止を管理する
+//
+// 停止は次の順に行う:
+//  1. OnDrain の関数を呼ぶ（準備完了の取り下げ）
+//  2. DrainDelay だけ待ち、HTTPサーバーと AddServer で登録したサーバーを停止する（処理中のリクエストの完了を待つ）
+//  3. Go で起動したバックグラウンド処理を止め、終了を待つ
+//  4. Add で登録した部品を登録順に停止する（ブローカー、データベースなど）
+type Manager struct {
+	config Config
+
+	mu         sync.Mutex
+	onDrain    []func()
+	servers    []additionalServer
+	workers    []*worker
+	components []component
+}
+
+// マネージャーの作成
+func NewManager(config Config) *Manager {
+	return &Manager{config: config}
+}
+
+// 停止の開始時に呼ぶ関数の登録
+func (m *Manager) OnDrain(f func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDrain = append(m.onDrain, f)
+}
+
+// 停止する部品の登録（HTTPサーバーとバックグラウンド処理の停止後、登録順に停止する）
+func (m *Manager) Add(name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// HTTPサーバーと並行して動かすサーバーの登録
+// Serve で listener を使って起動し、停止時は HTTPサーバーと同時に stop を呼ぶ（処理中の呼び出しの完了を待つ）
+func (m *Manager) AddServer(name string, listener net.Listener, serve func(net.Listener) error, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.servers = append(m.servers, additionalServer{name: name, listener: listener, serve: serve, stop: stop})
+}
+
+// バックグラウンド処理の起動（停止時に ctx がキャンセルされ、run が戻るまで待つ）
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+
+	m.mu.Lock()
+	m.workers = append(m.workers, w)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(w.done)
+		run(ctx)
+	}()
+}
+
+// HTTPサーバーを起動し、ctx がキャンセルされるかいずれかのサーバーが異常終了したら停止処理を行う
+func (m *Manager) Run(ctx context.Context, server *http.Server) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to listen: %w", err), m.shutdown(nil))
+	}
+	return m.Serve(ctx, server, listener)
+}
+
+// 指定したリスナーでHTTPサーバーを起動する（Run を参照）
+func (m *Manager) Serve(ctx context.Context, server *http.Server, listener net.Listener) error {
+	m.mu.Lock()
+	servers := append([]additionalServer{}, m.servers...)
+	m.mu.Unlock()
+
+	serveErr := make(chan error, 1+len(servers))
+	go func() {
+		serveErr <- fmt.Errorf("http server: %w", server.Serve(listener))
+	}()
+	for _, s := range servers {
+		go func() {
+			serveErr <- fmt.Errorf("%s: %w", s.name, s.serve(s.listener))
+		}()
+	}
+
+	// 1. 停止の合図かサーバーの異常終了を待つ
+	var errs []error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down", "reason", context.Cause(ctx))
+	case err := <-serveErr:
+		errs = append(errs, fmt.Errorf("server stopped: %w", err))
+	}
+
+	// 2. 停止処理
+	errs = append(errs, m.shutdown(server))
+	return errors.Join(errs...)
+}
+
+func (m *Manager) shutdown(server *http.Server) error {
+	m.mu.Lock()
+	onDrain := append([]func(){}, m.onDrain...)
+	servers := append([]additionalServer{}, m.servers...)
+	workers := append([]*worker{}, m.workers...)
+	components := append([]component{}, m.components...)
+	m.mu.Unlock()
+
+	var errs []error
+
+	// 1. 準備完了の取り下げ
+	for _, f := range onDrain {
+		f()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.GracePeriod)
+	defer cancel()
+
+	// 2. 新しい接続の受付を止め、処理中のリクエストの完了を待つ（各サーバーを同時に停止する）
+	if server != nil {
+		if m.config.DrainDelay > 0 {
+			select {
+			case <-time.After(m.config.DrainDelay):
+			case <-ctx.Done():
+			}
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		addErr := func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				addErr(fmt.Errorf("http server: %w", err))
+				// 猶予時間を過ぎた接続は切断する
+				server.Close()
+			}
+		}()
+		for _, s := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.stop(ctx); err != nil {
+					addErr(fmt.Errorf("%s: %w", s.name, err))
+				}
+			}()
+		}
+		wg.Wait()
+	} else {
+		// 起動前に失敗した場合はリスナーを閉じるだけでよい
+		for _, s := range servers {
+			s.listener.Close()
+		}
+	}
+
+	// 3. バックグラウンド処理の停止
+	for _, w := range workers {
+		w.cancel()
+	}
+	for _, w := range workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("%s: did not stop within the grace period", w.name))
+		}
+	}
+
+	// 4. 部品の停止（猶予時間を過ぎても接続を閉じるため、すべて呼び出す）
+	for _, c := range components {
+		if err := c.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		slog.Info("shutdown complete")
+	}
+	return errors.Join(errs...)
+}
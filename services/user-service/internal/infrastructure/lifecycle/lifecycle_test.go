@@ -0,0 +1,227 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 呼び出し順の記録
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, ",")
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	return listener
+}
+
+func TestManager_GracefulShutdown(t *testing.T) {
+	events := &recorder{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		events.add("request finished")
+		io.WriteString(w, "ok")
+	})}
+
+	m := NewManager(Config{GracePeriod: 5 * time.Second, DrainDelay: 10 * time.Millisecond})
+	m.OnDrain(func() { events.add("drain") })
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		events.add("worker stopped")
+	})
+	m.Add("broker", func(context.Context) error {
+		events.add("broker closed")
+		return nil
+	})
+	m.Add("database", func(context.Context) error {
+		events.add("database closed")
+		return nil
+	})
+
+	listener := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Serve(ctx, server, listener) }()
+
+	// 処理中のリクエストがある状態で停止を開始する
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+	cancel()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if got := <-response; got != "ok" {
+		t.Errorf("in-flight response = %q", got)
+	}
+	want := "drain,request finished,worker stopped,broker closed,database closed"
+	if got := events.String(); got != want {
+		t.Errorf("shutdown order = %q, want %q", got, want)
+	}
+}
+
+func TestManager_GracePeriodExceeded(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	var closed bool
+	m := NewManager(Config{GracePeriod: 50 * time.Millisecond})
+	// キャンセルされても戻らない処理
+	m.Go("stuck worker", func(context.Context) { <-block })
+	m.Add("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Serve(ctx, &http.Server{Handler: http.NotFoundHandler()}, listen(t))
+	if err == nil || !strings.Contains(err.Error(), "stuck worker") {
+		t.Errorf("Serve() error = %v", err)
+	}
+	// 猶予時間を過ぎても接続は閉じる
+	if !closed {
+		t.Error("database was not closed")
+	}
+}
+
+func TestManager_ServerError(t *testing.T) {
+	var stopped bool
+	m := NewManager(Config{GracePeriod: time.Second})
+	m.Add("database", func(context.Context) error {
+		stopped = true
+		return errors.New("close failed")
+	})
+
+	// 閉じたリスナーではサーバーが即座に終了する
+	listener := listen(t)
+	listener.Close()
+
+	err := m.Serve(context.Background(), &http.Server{Handler: http.NotFoundHandler()}, listener)
+	if err == nil || !strings.Contains(err.Error(), "server stopped") || !strings.Contains(err.Error(), "database: close failed") {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if !stopped {
+		t.Error("components were not stopped after the server failed")
+	}
+}
+
+func TestManager_AdditionalServer(t *testing.T) {
+	events := &recorder{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	// gRPC サーバーの代わりに 2 つ目の HTTPサーバーを使う
+	extra := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		events.add("extra request finished")
+		io.WriteString(w, "ok")
+	})}
+
+	m := NewManager(Config{GracePeriod: 5 * time.Second})
+	extraListener := listen(t)
+	m.AddServer("extra server", extraListener, extra.Serve, func(ctx context.Context) error {
+		err := extra.Shutdown(ctx)
+		events.add("extra server stopped")
+		return err
+	})
+	m.Add("database", func(context.Context) error {
+		events.add("database closed")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Serve(ctx, &http.Server{Handler: http.NotFoundHandler()}, listen(t)) }()
+
+	// 追加したサーバーで処理中の呼び出しがある状態で停止を開始する
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + extraListener.Addr().String())
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if got := <-response; got != "ok" {
+		t.Errorf("in-flight response = %q, want %q", got, "ok")
+	}
+	want := "extra request finished,extra server stopped,database closed"
+	if got := events.String(); got != want {
+		t.Errorf("shutdown order = %q, want %q", got, want)
+	}
+}
+
+func TestManager_AdditionalServerError(t *testing.T) {
+	var stopped bool
+	m := NewManager(Config{GracePeriod: time.Second})
+	// 閉じたリスナーでは追加したサーバーが即座に終了する
+	extraListener := listen(t)
+	extraListener.Close()
+	extra := &http.Server{Handler: http.NotFoundHandler()}
+	m.AddServer("extra server", extraListener, extra.Serve, func(ctx context.Context) error {
+		stopped = true
+		return extra.Shutdown(ctx)
+	})
+
+	err := m.Serve(context.Background(), &http.Server{Handler: http.NotFoundHandler()}, listen(t))
+	if err == nil || !strings.Contains(err.Error(), "server stopped: extra server") {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if !stopped {
+		t.Error("extra server was not stopped")
+	}
+}
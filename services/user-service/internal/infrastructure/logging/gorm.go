@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GORMのロガーの設定
+type GormConfig struct {
+	// これより遅いクエリを警告として出力する（0の場合は出力しない）
+	SlowThreshold time.Duration
+}
+
+// slog に出力するGORMのロガー
+// エラーと遅いクエリのみ出力し、その他のクエリは debug レベルで出力する
+type gormLogger struct {
+	logger *slog.Logger
+	config GormConfig
+	level  logger.LogLevel
+}
+
+// GORMのロガーの作成
+func NewGormLogger(l *slog.Logger, config GormConfig) logger.Interface {
+	return &gormLogger{logger: l, config: config, level: logger.Info}
+}
+
+func (g *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *g
+	copied.level = level
+	return &copied
+}
+
+func (g *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Info {
+		g.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Warn {
+		g.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Error {
+		g.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// クエリの出力（SQLに埋め込まれた値は伏せ字にする）
+func (g *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []slog.Attr {
+		sql, rows := fc()
+		return []slog.Attr{
+			slog.String("sql", Redact(sql)),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	// 見つからないことは呼び出し元で扱う正常な結果
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= logger.Error:
+		g.logger.LogAttrs(ctx, slog.LevelError, "query failed", append(attrs(), slog.String("error", err.Error()))...)
+	case g.config.SlowThreshold > 0 && elapsed > g.config.SlowThreshold && g.level >= logger.Warn:
+		g.logger.LogAttrs(ctx, slog.LevelWarn, "slow query", append(attrs(), slog.Duration("threshold", g.config.SlowThreshold))...)
+	case g.level >= logger.Info && g.logger.Enabled(ctx, slog.LevelDebug):
+		g.logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs()...)
+	}
+}
@@ -0,0 +1,94 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// メッセージ送信のインターフェース
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// 受信したイベントの処理関数（エラーを返すと再配信の対象になる）
+type Handler func(ctx context.Context, event Event) error
+
+// 購読の解除
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// メッセージ受信のインターフェース
+type Subscriber interface {
+	// eventType に一致するイベントを購読する（"*" はすべてのイベント）
+	Subscribe(ctx context.Context, eventType string, handler Handler) (Subscription, error)
+}
+
+// 送受信の両方を行うブローカー
+type Broker interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+// メモリ上に保持するパブリッシャー（テスト用）
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+// 送信されたイベントの一覧
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]Event, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+// ファイルにJSON Lines形式で追記するパブリッシャー（ローカル開発用）
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.file.Write(append(line, '\n'))
+	return err
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
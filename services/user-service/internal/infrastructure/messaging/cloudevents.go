@@ -0,0 +1,44 @@
+package messaging
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// CloudEvents 仕様のバージョン
+	CloudEventsSpecVersion = "1.0"
+	// 構造化モードのCloudEventsのContent-Type
+	ContentTypeCloudEventsJSON = "application/cloudevents+json"
+)
+
+// CloudEvents 1.0 形式のイベント（JSONの構造化モード）
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// イベントの作成（dataschema には現在のスキーマバージョンを設定する）
+func NewEvent(source, id, eventType, subject string, occurredAt time.Time, data json.RawMessage) Event {
+	event := Event{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            occurredAt.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if uri, ok := SchemaURI(eventType); ok {
+		event.DataSchema = uri
+	}
+	return event
+}
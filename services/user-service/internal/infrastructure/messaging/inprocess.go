@@ -0,0 +1,76 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// プロセス内で配信するブローカー（開発・テスト用）
+// Publish は購読者のハンドラーを同期的に呼び出し、ハンドラーのエラーを返す
+type InProcessBroker struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]inProcessSubscription
+}
+
+type inProcessSubscription struct {
+	eventType string
+	handler   Handler
+}
+
+func NewInProcessBroker() *InProcessBroker {
+	return &InProcessBroker{
+		subs: make(map[int]inProcessSubscription),
+	}
+}
+
+func (b *InProcessBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	var handlers []Handler
+	for _, sub := range b.subs {
+		if sub.eventType == "*" || sub.eventType == event.Type {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *InProcessBroker) Subscribe(_ context.Context, eventType string, handler Handler) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = inProcessSubscription{eventType: eventType, handler: handler}
+
+	return subscriptionFunc(func() error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+		return nil
+	}), nil
+}
+
+func (b *InProcessBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs = make(map[int]inProcessSubscription)
+	return nil
+}
+
+// 関数を Subscription として扱うアダプター
+type subscriptionFunc func() error
+
+func (f subscriptionFunc) Unsubscribe() error {
+	return f()
+}
@@ -0,0 +1,205 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+)
+
+func TestValidateData(t *testing.T) {
+	const userID = "6f1c4b8e-2d3a-4e5f-8a9b-0c1d2e3f4a5b"
+
+	tests := []struct {
+		name      string
+		eventType string
+		data      string
+		wantErr   bool
+	}{
+		{
+			name:      "valid registered event",
+			eventType: domain.EventUserRegistered,
+			data:      `{"user_id":"` + userID + `","registered_at":"2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name:      "missing required field",
+			eventType: domain.EventUserRegistered,
+			data:      `{"user_id":"` + userID + `"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "personal data is not allowed",
+			eventType: domain.EventUserRegistered,
+			data:      `{"user_id":"` + userID + `","email":"taro@example.com","name":"Taro","registered_at":"2024-01-01T00:00:00Z"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid uuid",
+			eventType: domain.EventUserDeleted,
+			data:      `{"user_id":"user-1","deleted_at":"2024-01-01T00:00:00Z"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid date-time",
+			eventType: domain.EventUserDeleted,
+			data:      `{"user_id":"` + userID + `","deleted_at":"yesterday"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown event type",
+			eventType: "user.unknown",
+			data:      `{}`,
+			wantErr:   true,
+		},
+		{
+			name:      "non-integer version",
+			eventType: domain.EventUserUpdated,
+			data:      `{"user_id":"` + userID + `","version":1.5,"updated_at":"2024-01-01T00:00:00Z"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "null value",
+			eventType: domain.EventUserDeleted,
+			data:      `{"user_id":null,"deleted_at":"2024-01-01T00:00:00Z"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "malformed json",
+			eventType: domain.EventUserDeleted,
+			data:      `{`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateData(tt.eventType, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 埋め込んだすべてのスキーマがコンパイルでき、$id がファイル名と一致するか
+func TestCompileSchemas(t *testing.T) {
+	schemas, err := compileSchemas()
+	if err != nil {
+		t.Fatalf("compileSchemas() error = %v", err)
+	}
+
+	names, err := fs.Glob(schemaFiles, "schemas/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schemas) != len(names) {
+		t.Errorf("compiled %d schemas from %d files", len(schemas), len(names))
+	}
+	for eventType, version := range currentSchemaVersions {
+		if _, ok := schemas[schemaURI(eventType, version)]; !ok {
+			t.Errorf("schema %s v%d is not embedded", eventType, version)
+		}
+	}
+	for _, name := range names {
+		data, err := schemaFiles.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var header struct {
+			ID string `json:"$id"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			t.Fatal(err)
+		}
+		base := strings.TrimSuffix(path.Base(name), ".json")
+		eventType, version, _ := strings.Cut(base, ".v")
+		if want := "urn:ecommerce:schema:" + eventType + ":v" + version; header.ID != want {
+			t.Errorf("%s: $id = %q, want %q", name, header.ID, want)
+		}
+	}
+}
+
+// ドメインイベントのJSONが現在のスキーマに適合するか
+func TestDomainEventsMatchSchemas(t *testing.T) {
+	const userID = "6f1c4b8e-2d3a-4e5f-8a9b-0c1d2e3f4a5b"
+	now := time.Now()
+
+	events := []domain.DomainEvent{
+		domain.UserRegistered{UserID: userID, RegisteredAt: now},
+		domain.UserUpdated{UserID: userID, Version: 2, UpdatedAt: now},
+		domain.UserDeleted{UserID: userID, DeletedAt: now},
+		domain.EmailVerified{UserID: userID, VerifiedAt: now},
+	}
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateData(event.EventType(), data); err != nil {
+			t.Errorf("%s: %v", event.EventType(), err)
+		}
+	}
+}
+
+func TestInProcessBroker(t *testing.T) {
+	ctx := context.Background()
+	broker := NewInProcessBroker()
+
+	var registered, all []string
+	if _, err := broker.Subscribe(ctx, domain.EventUserRegistered, func(_ context.Context, event Event) error {
+		registered = append(registered, event.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := broker.Subscribe(ctx, "*", func(_ context.Context, event Event) error {
+		all = append(all, event.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(id, eventType string) error {
+		return broker.Publish(ctx, NewEvent("/user-service", id, eventType, "user-1", time.Now(), json.RawMessage(`{}`)))
+	}
+
+	if err := publish("1", domain.EventUserRegistered); err != nil {
+		t.Fatal(err)
+	}
+	if err := publish("2", domain.EventUserDeleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 1 || len(all) != 2 {
+		t.Errorf("registered = %v, all = %v", registered, all)
+	}
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := publish("3", domain.EventUserDeleted); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("unsubscribed handler received %v", all)
+	}
+
+	t.Run("handler errors are returned", func(t *testing.T) {
+		errHandler := errors.New("handler failed")
+		if _, err := broker.Subscribe(ctx, domain.EventUserUpdated, func(context.Context, Event) error {
+			return errHandler
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := publish("4", domain.EventUserUpdated); !errors.Is(err, errHandler) {
+			t.Errorf("Publish() error = %v, want %v", err, errHandler)
+		}
+	})
+}
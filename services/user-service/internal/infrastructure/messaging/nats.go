@@ -0,0 +1,142 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS JetStream の設定
+type NATSConfig struct {
+	URL string
+	// イベントを保存するストリーム名
+	Stream string
+	// サブジェクトの接頭辞（イベント user.registered は "<prefix>.user.registered" に送信される）
+	SubjectPrefix string
+	// 購読時に作成する永続コンシューマー名の接頭辞（通常はサービス名）
+	Durable string
+}
+
+// NATS JetStream を使うブローカー
+type NATSBroker struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	config NATSConfig
+}
+
+// NATSに接続し、ストリームを作成する関数
+func NewNATSBroker(ctx context.Context, config NATSConfig) (*NATSBroker, error) {
+	nc, err := nats.Connect(config.URL, nats.Name(config.Durable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to initialize jetstream: %w", err)
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     config.Stream,
+		Subjects: []string{config.SubjectPrefix + ".>"},
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", config.Stream, err)
+	}
+
+	return &NATSBroker{
+		nc:     nc,
+		js:     js,
+		config: config,
+	}, nil
+}
+
+// イベントの種類に対応するサブジェクト
+func (b *NATSBroker) subject(eventType string) string {
+	if eventType == "*" {
+		return b.config.SubjectPrefix + ".>"
+	}
+	return b.config.SubjectPrefix + "." + eventType
+}
+
+// イベントの送信（イベントIDで重複排除される）
+func (b *NATSBroker) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(b.subject(event.Type))
+	msg.Data = data
+	msg.Header.Set("Content-Type", ContentTypeCloudEventsJSON)
+
+	if _, err := b.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID)); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// イベントの購読（ハンドラーが成功した場合のみACKし、失敗時は再配信される）
+func (b *NATSBroker) Subscribe(ctx context.Context, eventType string, handler Handler) (Subscription, error) {
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.config.Stream, jetstream.ConsumerConfig{
+		Durable:       durableName(b.config.Durable, eventType),
+		FilterSubject: b.subject(eventType),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer for %s: %w", eventType, err)
+	}
+
+	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
+		var event Event
+		if err := json.Unmarshal(msg.Data(), &event); err != nil {
+			// 解釈できないメッセージは再配信しても処理できないため破棄する
+			slog.Warn("discarding malformed event", "subject", msg.Subject(), "error", err)
+			msg.Term()
+			return
+		}
+
+		if err := handler(context.Background(), event); err != nil {
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume %s: %w", eventType, err)
+	}
+
+	return subscriptionFunc(func() error {
+		consumeCtx.Stop()
+		return nil
+	}), nil
+}
+
+// 接続の状態確認
+func (b *NATSBroker) Ping(ctx context.Context) error {
+	if !b.nc.IsConnected() {
+		return fmt.Errorf("nats connection is %s", b.nc.Status())
+	}
+	_, err := b.js.AccountInfo(ctx)
+	return err
+}
+
+func (b *NATSBroker) Close() error {
+	return b.nc.Drain()
+}
+
+// コンシューマー名に使えない文字を置き換える
+func durableName(prefix, eventType string) string {
+	name := prefix + "-" + eventType
+	if eventType == "*" {
+		name = prefix + "-all"
+	}
+	return strings.NewReplacer(".", "_", "*", "all", ">", "all", " ", "_").Replace(name)
+}
@@ -0,0 +1,123 @@
+package messaging
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// イベントの種類ごとの現在のスキーマバージョン
+// 互換性のない変更を行う場合は新しいバージョンのスキーマファイルを追加してここを更新する
+var currentSchemaVersions = map[string]int{
+	// v2: 個人情報（メールアドレス・氏名）を含めない
+	"user.registered":     2,
+	"user.updated":        2,
+	"user.deleted":        1,
+	"user.email_verified": 2,
+}
+
+// 現在のスキーマのURI
+func SchemaURI(eventType string) (string, bool) {
+	version, ok := currentSchemaVersions[eventType]
+	if !ok {
+		return "", false
+	}
+	return schemaURI(eventType, version), true
+}
+
+func schemaURI(eventType string, version int) string {
+	return fmt.Sprintf("urn:ecommerce:schema:%s:v%d", eventType, version)
+}
+
+// 指定したバージョンのスキーマ本体
+func Schema(eventType string, version int) ([]byte, error) {
+	data, err := schemaFiles.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", eventType, version))
+	if err != nil {
+		return nil, fmt.Errorf("schema %s v%d not found", eventType, version)
+	}
+	return data, nil
+}
+
+// 埋め込んだスキーマのコンパイル結果（URIごと、初回の検証時に作成する）
+var (
+	compileSchemasOnce sync.Once
+	compiledSchemas    map[string]*jsonschema.Schema
+	compileSchemasErr  error
+)
+
+// 埋め込んだすべてのスキーマを Draft 2020-12 としてコンパイルする（format も検証する）
+func compileSchemas() (map[string]*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+
+	// 1. $id をURIとして登録（スキーマ間の $ref もURIで解決する）
+	names, err := fs.Glob(schemaFiles, "schemas/*.json")
+	if err != nil {
+		return nil, err
+	}
+	uris := make([]string, 0, len(names))
+	for _, name := range names {
+		data, err := schemaFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var header struct {
+			ID string `json:"$id"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil || header.ID == "" {
+			return nil, fmt.Errorf("schema %s has no $id", name)
+		}
+		if err := compiler.AddResource(header.ID, bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("invalid schema %s: %w", name, err)
+		}
+		uris = append(uris, header.ID)
+	}
+
+	// 2. コンパイル
+	schemas := make(map[string]*jsonschema.Schema, len(uris))
+	for _, uri := range uris {
+		schema, err := compiler.Compile(uri)
+		if err != nil {
+			return nil, err
+		}
+		schemas[uri] = schema
+	}
+	return schemas, nil
+}
+
+// イベントのデータが現在のスキーマに適合するか検証する
+func ValidateData(eventType string, data []byte) error {
+	version, ok := currentSchemaVersions[eventType]
+	if !ok {
+		return fmt.Errorf("unknown event type: %s", eventType)
+	}
+
+	compileSchemasOnce.Do(func() {
+		compiledSchemas, compileSchemasErr = compileSchemas()
+	})
+	if compileSchemasErr != nil {
+		return compileSchemasErr
+	}
+	schema, ok := compiledSchemas[schemaURI(eventType, version)]
+	if !ok {
+		return fmt.Errorf("schema %s v%d not found", eventType, version)
+	}
+
+	// 整数の判定が丸めの影響を受けないよう json.Number として読み込む
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid event data: %w", err)
+	}
+	return schema.Validate(value)
+}
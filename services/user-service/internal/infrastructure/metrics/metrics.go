@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// メトリクス名の接頭辞
+const namespace = "user_service"
+
+// ルートに一致しなかったリクエストの route ラベル（パスをそのまま使うとラベルの種類が際限なく増えるため）
+const unmatchedRoute = "unmatched"
+
+// サービスのメトリクス
+// usecase.Metrics と auth.Observer を実装する
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	httpInFlight     prometheus.Gauge
+	grpcRequests     *prometheus.CounterVec
+	grpcDuration     *prometheus.HistogramVec
+	grpcInFlight     prometheus.Gauge
+	loginAttempts    *prometheus.CounterVec
+	tokensIssued     *prometheus.CounterVec
+	tokenValidations *prometheus.CounterVec
+	bcryptDuration   *prometheus.HistogramVec
+}
+
+// メトリクスの作成（Go ランタイムとプロセスのメトリクスも含む）
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route template, method and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Number of gRPC calls by full method name and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC call latency by full method name.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		grpcInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_in_flight",
+			Help:      "Number of gRPC calls currently being served.",
+		}),
+		loginAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_login_attempts_total",
+			Help:      "Number of login attempts by result and reason.",
+		}, []string{"result", "reason"}),
+		tokensIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_tokens_issued_total",
+			Help:      "Number of access tokens issued by method.",
+		}, []string{"method"}),
+		tokenValidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_token_validations_total",
+			Help:      "Number of access token validations by result.",
+		}, []string{"result"}),
+		bcryptDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "auth_bcrypt_duration_seconds",
+			Help:      "Latency of bcrypt password hashing and comparison.",
+			// bcrypt はコスト10で数十ミリ秒かかるため、既定より大きい範囲を使う
+			Buckets: []float64{.01, .025, .05, .1, .2, .3, .5, 1, 2},
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.grpcRequests,
+		m.grpcDuration,
+		m.grpcInFlight,
+		m.loginAttempts,
+		m.tokensIssued,
+		m.tokenValidations,
+		m.bcryptDuration,
+	)
+	return m
+}
+
+// コネクションプールの状態（sql.DBStats）の登録
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// メトリクスの登録（他のパッケージのメトリクス用）
+func (m *Metrics) Register(collector prometheus.Collector) error {
+	return m.registry.Register(collector)
+}
+
+// /metrics のハンドラー
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// HTTPリクエストの計測（ルートのテンプレートごとに集計する）
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		m.httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// gRPC の単項呼び出しの計測（メソッドの完全名ごとに集計する）
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		m.grpcInFlight.Inc()
+		defer m.grpcInFlight.Dec()
+
+		res, err := handler(ctx, req)
+		m.observeGRPC(info.FullMethod, err, start)
+		return res, err
+	}
+}
+
+// gRPC のストリームの計測（ストリームが終了するまでの時間を記録する）
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		m.grpcInFlight.Inc()
+		defer m.grpcInFlight.Dec()
+
+		err := handler(srv, ss)
+		m.observeGRPC(info.FullMethod, err, start)
+		return err
+	}
+}
+
+// 登録されたメソッドのみがインターセプターを通るため、メソッド名をそのままラベルに使う
+func (m *Metrics) observeGRPC(method string, err error, start time.Time) {
+	m.grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// ログインの試行の記録
+func (m *Metrics) LoginAttempt(result, reason string) {
+	m.loginAttempts.WithLabelValues(result, reason).Inc()
+}
+
+// bcrypt の処理時間の記録
+func (m *Metrics) BcryptDuration(operation string, d time.Duration) {
+	m.bcryptDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// トークンの発行の記録
+func (m *Metrics) TokenIssued(method string) {
+	m.tokensIssued.WithLabelValues(method).Inc()
+}
+
+// トークンの検証の記録
+func (m *Metrics) TokenValidated(result string) {
+	m.tokenValidations.WithLabelValues(result).Inc()
+}
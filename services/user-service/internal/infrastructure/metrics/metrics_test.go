@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware_UsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/users/1", "/users/2", "/unknown/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/users/:id", "200")); got != 2 {
+		t.Errorf("requests for /users/:id = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.httpInFlight); got != 0 {
+		t.Errorf("in-flight = %v, want 0", got)
+	}
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	m := New()
+	unary := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.v1.UserService/GetUser"}
+
+	unary(context.Background(), nil, info, func(context.Context, any) (any, error) { return "ok", nil })
+	unary(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	m.StreamServerInterceptor()(nil, nil, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, func(any, grpc.ServerStream) error {
+		return nil
+	})
+
+	for _, tt := range []struct {
+		method, code string
+	}{
+		{"/user.v1.UserService/GetUser", "OK"},
+		{"/user.v1.UserService/GetUser", "NotFound"},
+		{"/grpc.health.v1.Health/Watch", "OK"},
+	} {
+		if got := testutil.ToFloat64(m.grpcRequests.WithLabelValues(tt.method, tt.code)); got != 1 {
+			t.Errorf("calls for %s %s = %v, want 1", tt.method, tt.code, got)
+		}
+	}
+	if got := testutil.CollectAndCount(m.grpcDuration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.grpcInFlight); got != 0 {
+		t.Errorf("in-flight = %v, want 0", got)
+	}
+}
+
+func TestHandler_ExposesMetrics(t *testing.T) {
+	m := New()
+	m.LoginAttempt("failure", "invalid_password")
+	m.TokenIssued("password")
+	m.TokenValidated("expired")
+	m.BcryptDuration("compare", 50*time.Millisecond)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	if err := m.RegisterDB("sqlite", db); err != nil {
+		t.Fatalf("RegisterDB() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	for _, want := range []string{
+		`user_service_auth_login_attempts_total{reason="invalid_password",result="failure"} 1`,
+		`user_service_auth_tokens_issued_total{method="password"} 1`,
+		`user_service_auth_token_validations_total{result="expired"} 1`,
+		`user_service_auth_bcrypt_duration_seconds_count{operation="compare"} 1`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics does not contain %q", want)
+		}
+	}
+}
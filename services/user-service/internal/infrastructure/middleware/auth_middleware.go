@@ -1,9 +1,13 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
 	"github.com/gin-gonic/gin"
 )
@@ -23,52 +27,115 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 		// 1. Authorizationヘッダーの取得
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authorization header is required").
+				WithMessage("authorization_header_required", nil))
 			return
 		}
 
 		// 2. Bearer tokenの形式チェック
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			c.Abort()
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid authorization format").
+				WithMessage("invalid_authorization_format", nil))
 			return
 		}
 
 		// 3. トークンの検証
 		claims, err := m.jwtService.ValidateToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired token"))
 			return
 		}
 
 		// 4. ユーザー情報をコンテキストに設定
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("scopes", claims.Scopes())
+		c.Set("claims", claims)
+
+		// 5. ユーザーが希望する言語を Accept-Language より優先する
+		if i18n.Supported(claims.Locale) {
+			setLocale(c, claims.Locale)
+		}
 
 		c.Next()
 	}
 }
 
+// 指定したスコープをすべて要求するミドルウェア（AuthRequired の後に使用）
+func (m *AuthMiddleware) RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		claims, ok := value.(*auth.JWTClaims)
+		if !exists || !ok {
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
+			return
+		}
+
+		if !claims.HasScopes(scopes...) {
+			// RFC 6750 に従い不足しているスコープを通知する
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+			apierror.Write(c, apierror.New(http.StatusForbidden, apierror.CodeInsufficientScope, "Insufficient scope"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// 最近の再認証を要求するミドルウェア（AuthRequired の後に使用）
+// acrs を指定した場合はそのいずれかの方式で認証されている必要がある
+func (m *AuthMiddleware) RequireRecentAuth(maxAge time.Duration, acrs ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		claims, ok := value.(*auth.JWTClaims)
+		if !exists || !ok {
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
+			return
+		}
+
+		if !claims.AuthenticatedWithin(maxAge) || !acceptedACR(claims.ACR, acrs) {
+			// RFC 9470 のステップアップ認証チャレンジ
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int(maxAge.Seconds())))
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeReauthenticationRequired, "Reauthentication required"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func acceptedACR(acr string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, a := range accepted {
+		if a == acr {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *AuthMiddleware) RefreshToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authorization header is required").
+				WithMessage("authorization_header_required", nil))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid authorization format").
+				WithMessage("invalid_authorization_format", nil))
 			return
 		}
 
 		newToken, err := m.jwtService.RefreshToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid or expired token"))
 			return
 		}
 
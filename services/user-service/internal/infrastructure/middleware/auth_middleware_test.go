@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestJWTService() *auth.JWTService {
+	return auth.NewJWTService(auth.Config{
+		SecretKey:        "test-secret",
+		Expires:          time.Hour,
+		Issuer:           "user-service",
+		Audience:         []string{"user-service"},
+		AcceptedAudience: "user-service",
+		DefaultScopes:    []string{auth.ScopeProfileRead},
+	})
+}
+
+// 認証後のコンテキストを返すハンドラー
+func echoContext(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"userID": c.GetString("userID"),
+		"email":  c.GetString("email"),
+		"scopes": c.GetStringSlice("scopes"),
+	})
+}
+
+func serve(router *gin.Engine, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthMiddleware_AuthRequired(t *testing.T) {
+	jwtService := newTestJWTService()
+	m := NewAuthMiddleware(jwtService)
+
+	router := gin.New()
+	router.GET("/", m.AuthRequired(), echoContext)
+
+	token, err := jwtService.GenerateToken("user-1", "taro@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantCode      string
+	}{
+		{name: "valid token", authorization: "Bearer " + token, wantStatus: http.StatusOK},
+		{name: "missing header", authorization: "", wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeUnauthorized},
+		{name: "wrong scheme", authorization: "Basic " + token, wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeInvalidToken},
+		{name: "missing token", authorization: "Bearer", wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeInvalidToken},
+		{name: "invalid token", authorization: "Bearer invalid", wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, "/", tt.authorization)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, apierror.ContentType) {
+				t.Errorf("Content-Type = %q", got)
+			}
+			var p apierror.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.Code != tt.wantCode {
+				t.Errorf("body = %s, want code %q", rec.Body, tt.wantCode)
+			}
+		})
+	}
+
+	t.Run("sets claims in context", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, "/", "Bearer "+token)
+
+		var body struct {
+			UserID string   `json:"userID"`
+			Email  string   `json:"email"`
+			Scopes []string `json:"scopes"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.UserID != "user-1" || body.Email != "taro@example.com" {
+			t.Errorf("context = %+v", body)
+		}
+		if len(body.Scopes) != 1 || body.Scopes[0] != auth.ScopeProfileRead {
+			t.Errorf("scopes = %v", body.Scopes)
+		}
+	})
+}
+
+func TestAuthMiddleware_RequireScope(t *testing.T) {
+	jwtService := newTestJWTService()
+	m := NewAuthMiddleware(jwtService)
+
+	router := gin.New()
+	router.GET("/read", m.AuthRequired(), m.RequireScope(auth.ScopeProfileRead), echoContext)
+	router.GET("/write", m.AuthRequired(), m.RequireScope(auth.ScopeProfileWrite), echoContext)
+	router.GET("/unauthenticated", m.RequireScope(auth.ScopeProfileRead), echoContext)
+
+	token, err := jwtService.GenerateToken("user-1", "taro@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := serve(router, http.MethodGet, "/read", "Bearer "+token); rec.Code != http.StatusOK {
+		t.Errorf("granted scope: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec := serve(router, http.MethodGet, "/write", "Bearer "+token)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("missing scope: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `error="insufficient_scope"`) {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+
+	if rec := serve(router, http.MethodGet, "/unauthenticated", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without AuthRequired: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthMiddleware_RequireRecentAuth(t *testing.T) {
+	jwtService := newTestJWTService()
+	m := NewAuthMiddleware(jwtService)
+
+	router := gin.New()
+	router.GET("/sensitive", m.AuthRequired(), m.RequireRecentAuth(5*time.Minute), echoContext)
+	router.GET("/mfa-only", m.AuthRequired(), m.RequireRecentAuth(5*time.Minute, auth.ACRMFA), echoContext)
+
+	issue := func(authTime time.Time, acr string) string {
+		token, err := jwtService.IssueToken(auth.TokenParams{UserID: "user-1", Email: "taro@example.com", AuthTime: authTime, ACR: acr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "recent password auth", path: "/sensitive", token: issue(time.Now(), auth.ACRPassword), wantStatus: http.StatusOK},
+		{name: "stale auth", path: "/sensitive", token: issue(time.Now().Add(-time.Hour), auth.ACRPassword), wantStatus: http.StatusUnauthorized},
+		{name: "no auth_time", path: "/sensitive", token: issue(time.Time{}, ""), wantStatus: http.StatusUnauthorized},
+		{name: "password when mfa required", path: "/mfa-only", token: issue(time.Now(), auth.ACRPassword), wantStatus: http.StatusUnauthorized},
+		{name: "recent mfa", path: "/mfa-only", token: issue(time.Now(), auth.ACRMFA), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodGet, tt.path, "Bearer "+tt.token)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && !strings.Contains(rec.Header().Get("WWW-Authenticate"), "insufficient_user_authentication") {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RefreshToken(t *testing.T) {
+	jwtService := newTestJWTService()
+	m := NewAuthMiddleware(jwtService)
+
+	router := gin.New()
+	router.POST("/refresh", m.RefreshToken())
+
+	token, err := jwtService.GenerateToken("user-1", "taro@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(router, http.MethodPost, "/refresh", "Bearer "+token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtService.ValidateToken(body.Token); err != nil {
+		t.Errorf("refreshed token is invalid: %v", err)
+	}
+
+	if rec := serve(router, http.MethodPost, "/refresh", "Bearer invalid"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := serve(router, http.MethodPost, "/refresh", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing header: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストの言語の選択
+// Accept-Language に対応する言語がない場合は defaultLocale を使う。
+// 認証後は AuthRequired がユーザーの希望する言語に置き換える。
+func Locale(defaultLocale string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale, ok := i18n.Match(c.GetHeader("Accept-Language"))
+		if !ok {
+			locale = defaultLocale
+		}
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		setLocale(c, locale)
+
+		c.Next()
+	}
+}
+
+// リクエストの言語を設定し、応答の Content-Language で通知する
+func setLocale(c *gin.Context, locale string) {
+	c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+	c.Header("Content-Language", locale)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLocale(t *testing.T) {
+	jwtService := newTestJWTService()
+	m := NewAuthMiddleware(jwtService)
+
+	echoLocale := func(c *gin.Context) {
+		c.String(http.StatusOK, i18n.LocaleFromContext(c.Request.Context()))
+	}
+	router := gin.New()
+	router.Use(Locale(i18n.Japanese))
+	router.GET("/public", echoLocale)
+	router.GET("/private", m.AuthRequired(), echoLocale)
+
+	englishUser, err := jwtService.GeneratePasswordToken(auth.TokenParams{UserID: "user-1", Locale: i18n.English})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noPreference, err := jwtService.GeneratePasswordToken(auth.TokenParams{UserID: "user-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptLanguage string
+		token          string
+		want           string
+	}{
+		{name: "default", path: "/public", want: i18n.Japanese},
+		{name: "accept-language", path: "/public", acceptLanguage: "en-US,en;q=0.9", want: i18n.English},
+		{name: "unsupported language", path: "/public", acceptLanguage: "fr", want: i18n.Japanese},
+		// ユーザーの希望する言語を Accept-Language より優先する
+		{name: "user preference", path: "/private", acceptLanguage: "ja", token: englishUser, want: i18n.English},
+		{name: "no user preference", path: "/private", acceptLanguage: "en", token: noPreference, want: i18n.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("locale = %q, want %q", got, tt.want)
+			}
+			if got := rec.Header().Get("Content-Language"); got != tt.want {
+				t.Errorf("Content-Language = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
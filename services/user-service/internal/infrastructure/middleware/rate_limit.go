@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// リクエストから制限の単位となるキーを求める関数
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// クライアントのIPアドレスごと（信頼するプロキシの設定に従う）
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// 認証済みのユーザーごと（未認証の場合はIPアドレスごと）
+func KeyByUserID(c *gin.Context) string {
+	if userID := c.GetString("userID"); userID != "" {
+		return "user:" + userID
+	}
+	return KeyByIP(c)
+}
+
+// ルートごとの制限
+type RateLimitPolicy struct {
+	// 制限の名前（保存先のキーに含め、ポリシー間で状態を分ける）
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateLimitKeyFunc
+}
+
+// レート制限
+// 応答に RateLimit-* ヘッダーを付与し、超過した場合は 429 と Retry-After を返す。
+// 保存先の障害時はサービスを止めないよう制限せずに通す。
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) gin.HandlerFunc {
+	key := policy.Key
+	if key == nil {
+		key = KeyByIP
+	}
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit.Requests, int(policy.Limit.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		result, err := store.Allow(c.Request.Context(), policy.Name+":"+key(c), policy.Limit)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limit check failed", "policy", policy.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		// 1. 制限の状態（IETF draft-ietf-httpapi-ratelimit-headers）
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		// 2. 超過した場合は拒否する
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			apierror.Write(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// 秒単位に切り上げる
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// 常に失敗する保存先（テスト用）
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func newRateLimitRouter(store ratelimit.Store, key RateLimitKeyFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("userID", userID)
+		}
+	})
+	router.Use(RateLimit(store, RateLimitPolicy{
+		Name:  "test",
+		Limit: ratelimit.Limit{Requests: 2, Window: time.Minute},
+		Key:   key,
+	}))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestRateLimit(t *testing.T) {
+	router := newRateLimitRouter(ratelimit.NewMemoryStore(), KeyByIP)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 1. 上限までは残り回数を通知して通す
+	for _, remaining := range []string{"1", "0"} {
+		w := send()
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("RateLimit-Remaining = %q, want %q", got, remaining)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("RateLimit-Limit = %q, want 2", got)
+		}
+		if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+			t.Errorf("RateLimit-Policy = %q, want 2;w=60", got)
+		}
+	}
+
+	// 2. 超過したら 429 と Retry-After を返す
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("RateLimit-Reset = %q, want 60", got)
+	}
+}
+
+func TestRateLimit_Keys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  RateLimitKeyFunc
+		// 別々に数えられるべき2つのクライアントのリクエスト
+		first, second func(req *http.Request)
+	}{
+		{
+			name:   "by IP",
+			key:    KeyByIP,
+			first:  func(req *http.Request) { req.RemoteAddr = "192.0.2.1:1234" },
+			second: func(req *http.Request) { req.RemoteAddr = "192.0.2.2:1234" },
+		},
+		{
+			name:   "by user",
+			key:    KeyByUserID,
+			first:  func(req *http.Request) { req.Header.Set("X-Test-User", "user-1") },
+			second: func(req *http.Request) { req.Header.Set("X-Test-User", "user-2") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRateLimitRouter(ratelimit.NewMemoryStore(), tt.key)
+			send := func(prepare func(req *http.Request)) int {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				prepare(req)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			for i := 0; i < 2; i++ {
+				send(tt.first)
+			}
+			if code := send(tt.first); code != http.StatusTooManyRequests {
+				t.Fatalf("expected first client to be limited, got %d", code)
+			}
+			if code := send(tt.second); code != http.StatusNoContent {
+				t.Errorf("expected second client to be allowed, got %d", code)
+			}
+		})
+	}
+}
+
+func TestRateLimit_FailOpen(t *testing.T) {
+	router := newRateLimitRouter(failingStore{}, KeyByIP)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected request to pass when the store fails, got %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("expected no RateLimit headers, got RateLimit-Limit = %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// リクエストIDのヘッダー
+const HeaderRequestID = "X-Request-ID"
+
+// リクエストIDの付与
+// クライアントやゲートウェイから受け取った X-Request-ID を引き継ぎ、ない場合は生成する
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if !logging.ValidRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set("requestID", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(HeaderRequestID, id)
+
+		c.Next()
+	}
+}
+
+// アクセスログの出力（クエリ文字列のメールアドレスなどは伏せ字にする）
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if query := c.Request.URL.RawQuery; query != "" {
+			// エンコードされたメールアドレスも伏せ字の対象にするためデコードして出力する
+			if decoded, err := url.QueryUnescape(query); err == nil {
+				query = decoded
+			}
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		if errs := c.Errors.String(); errs != "" {
+			attrs = append(attrs, slog.String("errors", errs))
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Config{Level: "info"})
+	if err != nil {
+		t.Fatalf("logging.New() error = %v", err)
+	}
+
+	var seen string
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(logger))
+	router.GET("/users", func(c *gin.Context) {
+		seen = logging.RequestIDFromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "propagated", header: "gateway-42"},
+		{name: "generated", generate: true},
+		// ログに改行などを混入させない
+		{name: "rejected", header: "bad\nvalue", generate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/users?email=frank%40example.com", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(HeaderRequestID)
+			if tt.generate && (id == "" || id == tt.header) {
+				t.Errorf("expected a generated request ID, got %q", id)
+			}
+			if !tt.generate && id != tt.header {
+				t.Errorf("request ID = %q, want %q", id, tt.header)
+			}
+			if seen != id {
+				t.Errorf("context request ID = %q, want %q", seen, id)
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid access log %q: %v", buf.String(), err)
+			}
+			if entry["request_id"] != id || entry["status"] != float64(http.StatusNoContent) || entry["path"] != "/users" {
+				t.Errorf("access log = %v", entry)
+			}
+			if strings.Contains(buf.String(), "frank") {
+				t.Errorf("access log leaked an email: %s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+)
+
+// 送信するメール
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// メール送信のインターフェース
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ログに出力するだけのメーラー（開発環境用）
+type logMailer struct{}
+
+func NewLogMailer() Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail sent", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/messaging"
+)
+
+// リレーの設定
+type Config struct {
+	// CloudEvents の source 属性
+	Source string
+	// 未送信メッセージを確認する間隔
+	PollInterval time.Duration
+	// 1回のトランザクションで送信する最大件数
+	BatchSize int
+	// 再送間隔の基準値（失敗回数に応じて倍増する）
+	RetryBaseDelay time.Duration
+	// 再送間隔の上限
+	RetryMaxDelay time.Duration
+}
+
+// アウトボックスのメッセージをブローカーに送信するワーカー
+// 送信後のコミットに失敗した場合は再送されるため、配信は at-least-once となる
+type Relay struct {
+	outboxRepo domain.OutboxRepository
+	txManager  domain.TxManager
+	publisher  messaging.Publisher
+	config     Config
+}
+
+// リレーの作成
+func NewRelay(repo domain.OutboxRepository, txManager domain.TxManager, publisher messaging.Publisher, config Config) *Relay {
+	return &Relay{
+		outboxRepo: repo,
+		txManager:  txManager,
+		publisher:  publisher,
+		config:     config,
+	}
+}
+
+// ctx がキャンセルされるまで送信を繰り返す
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// バッチが埋まっている間は待たずに続けて処理する
+		for {
+			n, err := r.ProcessBatch(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "outbox relay failed", "error", err)
+				break
+			}
+			if n < r.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 未送信メッセージを1バッチ分送信し、処理した件数を返す
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	processed := 0
+
+	err := r.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		processed = 0
+
+		messages, err := r.outboxRepo.FetchPending(ctx, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			// スキーマに適合しないイベントは送信せず、失敗として記録する
+			publishErr := messaging.ValidateData(m.EventType, m.Payload)
+			if publishErr == nil {
+				event := messaging.NewEvent(r.config.Source, m.ID, m.EventType, m.AggregateID, m.OccurredAt, m.Payload)
+				publishErr = r.publisher.Publish(ctx, event)
+			}
+
+			if publishErr != nil {
+				next := time.Now().Add(r.backoff(m.Attempts + 1))
+				if err := r.outboxRepo.MarkFailed(ctx, m.ID, publishErr.Error(), next); err != nil {
+					return err
+				}
+			} else if err := r.outboxRepo.MarkPublished(ctx, m.ID); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+
+	return processed, err
+}
+
+// 失敗回数に応じた再送までの待ち時間
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.RetryBaseDelay
+	for i := 1; i < attempts && delay < r.config.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.RetryMaxDelay {
+		delay = r.config.RetryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/messaging"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"github.com/google/uuid"
+)
+
+func TestRelay_ProcessBatch(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.NewSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	if err := persistence.AutoMigrateModels(db); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := persistence.NewOutboxRepository(db)
+	publisher := messaging.NewMemoryPublisher()
+	relay := NewRelay(repo, persistence.NewTxManager(db), publisher, Config{
+		Source:         "/user-service",
+		BatchSize:      10,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+	})
+
+	userID := uuid.New().String()
+	if err := repo.Add(ctx, domain.UserRegistered{UserID: userID, RegisteredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	// スキーマに適合しないイベント（user_id が UUID ではない）
+	if err := repo.Add(ctx, domain.UserDeleted{UserID: "not-a-uuid", DeletedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := relay.ProcessBatch(ctx)
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("processed %d messages, want 2", n)
+	}
+
+	events := publisher.Events()
+	if len(events) != 1 {
+		t.Fatalf("published %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Type != domain.EventUserRegistered || event.Subject != userID || event.Source != "/user-service" {
+		t.Errorf("event = %+v", event)
+	}
+	if event.SpecVersion != messaging.CloudEventsSpecVersion || event.DataSchema == "" {
+		t.Errorf("event is missing CloudEvents attributes: %+v", event)
+	}
+
+	// 送信済みのメッセージと再試行待ちのメッセージは再送されない
+	if n, err := relay.ProcessBatch(ctx); err != nil || n != 0 {
+		t.Errorf("second ProcessBatch() = %d, %v, want 0, nil", n, err)
+	}
+	if len(publisher.Events()) != 1 {
+		t.Errorf("published %d events after second batch, want 1", len(publisher.Events()))
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(nil, nil, nil, Config{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 5, want: 10 * time.Second},
+		{attempts: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := relay.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
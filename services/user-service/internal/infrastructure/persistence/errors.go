@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"errors"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQLのエラーコード（SQLSTATE）
+const (
+	sqlStateUniqueViolation      = "23505"
+	sqlStateForeignKeyViolation  = "23503"
+	sqlStateNotNullViolation     = "23502"
+	sqlStateCheckViolation       = "23514"
+	sqlStateStringDataTruncation = "22001"
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// 一意制約とドメインエラーの対応
+// PostgreSQLは制約名、SQLiteは "テーブル.列" で識別する
+var uniqueConstraintErrors = map[string]error{
+	"idx_user_models_email":       domain.ErrEmailAlreadyExists,
+	"user_models.email":           domain.ErrEmailAlreadyExists,
+	"idx_user_models_email_index": domain.ErrEmailAlreadyExists,
+	"user_models.email_index":     domain.ErrEmailAlreadyExists,
+}
+
+// ドライバーのエラーを保持したままドメインエラーに変換したエラー
+// errors.Is でドメインエラーとドライバーのエラーの両方を判定できる
+type dbError struct {
+	domainErr error
+	cause     error
+}
+
+func (e *dbError) Error() string {
+	return e.domainErr.Error() + ": " + e.cause.Error()
+}
+
+func (e *dbError) Unwrap() []error {
+	return []error{e.domainErr, e.cause}
+}
+
+// データベースのエラーをドメインエラーに変換するヘルパー関数
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if translated, ok := translateSQLiteError(err); ok {
+		return translated
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var domainErr error
+	switch pgErr.Code {
+	case sqlStateUniqueViolation:
+		domainErr = domain.ErrConflict
+		if mapped, ok := uniqueConstraintErrors[pgErr.ConstraintName]; ok {
+			domainErr = mapped
+		}
+	case sqlStateForeignKeyViolation:
+		domainErr = domain.ErrReferenceViolation
+	case sqlStateNotNullViolation, sqlStateCheckViolation, sqlStateStringDataTruncation:
+		domainErr = domain.ErrConstraintViolation
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		domainErr = domain.ErrSerializationFailure
+	default:
+		return err
+	}
+
+	return &dbError{domainErr: domainErr, cause: err}
+}
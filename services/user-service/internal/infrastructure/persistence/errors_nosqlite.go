@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package persistence
+
+// SQLiteのドライバーはcgoを必要とするため、cgoなしのビルドでは変換しない
+func translateSQLiteError(err error) (error, bool) {
+	return err, false
+}
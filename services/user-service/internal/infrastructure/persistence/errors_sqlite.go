@@ -0,0 +1,45 @@
+//go:build cgo
+
+package persistence
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteのエラーをドメインエラーに変換する
+// SQLiteのエラーでない場合は false を返す
+func translateSQLiteError(err error) (error, bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err, false
+	}
+
+	var domainErr error
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		domainErr = domain.ErrConflict
+		// メッセージの形式: "UNIQUE constraint failed: user_models.email"
+		if _, columns, ok := strings.Cut(sqliteErr.Error(), "constraint failed: "); ok {
+			if mapped, ok := uniqueConstraintErrors[columns]; ok {
+				domainErr = mapped
+			}
+		}
+	case sqlite3.ErrConstraintForeignKey:
+		domainErr = domain.ErrReferenceViolation
+	case sqlite3.ErrConstraintNotNull, sqlite3.ErrConstraintCheck:
+		domainErr = domain.ErrConstraintViolation
+	default:
+		switch sqliteErr.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked:
+			domainErr = domain.ErrSerializationFailure
+		default:
+			return err, true
+		}
+	}
+
+	return &dbError{domainErr: domainErr, cause: err}, true
+}
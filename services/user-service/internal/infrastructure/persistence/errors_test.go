@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestTranslateError_Postgres(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *pgconn.PgError
+		wantErr error
+	}{
+		{name: "email unique violation", err: &pgconn.PgError{Code: sqlStateUniqueViolation, ConstraintName: "idx_user_models_email_index"}, wantErr: domain.ErrEmailAlreadyExists},
+		{name: "legacy email unique violation", err: &pgconn.PgError{Code: sqlStateUniqueViolation, ConstraintName: "idx_user_models_email"}, wantErr: domain.ErrEmailAlreadyExists},
+		{name: "other unique violation", err: &pgconn.PgError{Code: sqlStateUniqueViolation, ConstraintName: "outbox_message_models_pkey"}, wantErr: domain.ErrConflict},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: sqlStateForeignKeyViolation}, wantErr: domain.ErrReferenceViolation},
+		{name: "not null violation", err: &pgconn.PgError{Code: sqlStateNotNullViolation}, wantErr: domain.ErrConstraintViolation},
+		{name: "check violation", err: &pgconn.PgError{Code: sqlStateCheckViolation}, wantErr: domain.ErrConstraintViolation},
+		{name: "string too long", err: &pgconn.PgError{Code: sqlStateStringDataTruncation}, wantErr: domain.ErrConstraintViolation},
+		{name: "serialization failure", err: &pgconn.PgError{Code: sqlStateSerializationFailure}, wantErr: domain.ErrSerializationFailure},
+		{name: "deadlock", err: &pgconn.PgError{Code: sqlStateDeadlockDetected}, wantErr: domain.ErrSerializationFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// gorm などでラップされていても判定できる
+			err := translateError(fmt.Errorf("query failed: %w", tt.err))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("translateError() = %v, want %v", err, tt.wantErr)
+			}
+			// 元のドライバーのエラーも取り出せる
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) || pgErr != tt.err {
+				t.Errorf("translateError() lost the driver error: %v", err)
+			}
+		})
+	}
+
+	t.Run("unmapped errors are returned unchanged", func(t *testing.T) {
+		syntaxErr := &pgconn.PgError{Code: "42601"}
+		if err := translateError(syntaxErr); err != syntaxErr {
+			t.Errorf("translateError() = %v, want the original error", err)
+		}
+		plain := errors.New("connection refused")
+		if err := translateError(plain); err != plain {
+			t.Errorf("translateError() = %v, want the original error", err)
+		}
+		if err := translateError(nil); err != nil {
+			t.Errorf("translateError(nil) = %v", err)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ログイン確認のテーブル構造
+type LoginChallengeModel struct {
+	ID           string    `gorm:"primaryKey;type:uuid"`
+	UserID       string    `gorm:"type:uuid;not null;index"`
+	LoginEventID string    `gorm:"type:uuid;not null"`
+	CodeHash     string    `gorm:"not null"`
+	Attempts     int       `gorm:"not null;default:0"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// リポジトリの構造体
+type loginChallengeRepository struct {
+	db *gorm.DB
+}
+
+// リポジトリを作成する関数
+func NewLoginChallengeRepository(db *gorm.DB) domain.LoginChallengeRepository {
+	return &loginChallengeRepository{
+		db: db,
+	}
+}
+
+// DBモデルをドメインモデルに変換
+func toLoginChallengeDomain(model *LoginChallengeModel) *domain.LoginChallenge {
+	return &domain.LoginChallenge{
+		ID:           model.ID,
+		UserID:       model.UserID,
+		LoginEventID: model.LoginEventID,
+		CodeHash:     model.CodeHash,
+		Attempts:     model.Attempts,
+		ExpiresAt:    model.ExpiresAt,
+		CreatedAt:    model.CreatedAt,
+	}
+}
+
+// ログイン確認の保存（同じユーザーの以前の確認は削除する）
+func (r *loginChallengeRepository) Create(ctx context.Context, challenge *domain.LoginChallenge) error {
+	if challenge.ID == "" {
+		challenge.ID = uuid.New().String()
+	}
+
+	model := &LoginChallengeModel{
+		ID:           challenge.ID,
+		UserID:       challenge.UserID,
+		LoginEventID: challenge.LoginEventID,
+		CodeHash:     challenge.CodeHash,
+		Attempts:     challenge.Attempts,
+		ExpiresAt:    challenge.ExpiresAt,
+		CreatedAt:    challenge.CreatedAt,
+	}
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", challenge.UserID).Delete(&LoginChallengeModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(model).Error
+	})
+	return translateError(err)
+}
+
+// ユーザーの最新のログイン確認を取得
+func (r *loginChallengeRepository) FindLatestByUserID(ctx context.Context, userID string) (*domain.LoginChallenge, error) {
+	var model LoginChallengeModel
+	result := conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, translateError(result.Error)
+	}
+	return toLoginChallengeDomain(&model), nil
+}
+
+// 誤ったコードの入力回数を1増やす
+func (r *loginChallengeRepository) IncrementAttempts(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).
+		Model(&LoginChallengeModel{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return translateError(result.Error)
+}
+
+// ログイン確認の削除
+func (r *loginChallengeRepository) Delete(ctx context.Context, id string) error {
+	return translateError(conn(ctx, r.db).Where("id = ?", id).Delete(&LoginChallengeModel{}).Error)
+}
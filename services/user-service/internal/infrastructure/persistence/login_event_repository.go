@@ -0,0 +1,123 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ログインイベントのテーブル構造
+type LoginEventModel struct {
+	ID        string `gorm:"primaryKey;type:uuid"`
+	UserID    string `gorm:"type:uuid;not null;index:idx_login_event_models_user_created,priority:1"`
+	IPAddress string `gorm:"not null"`
+	UserAgent string `gorm:"not null"`
+	DeviceID  string `gorm:"not null"`
+	Country   string
+	City      string
+	Latitude  *float64
+	Longitude *float64
+	// カンマ区切りのフラグ
+	Flags     string
+	Succeeded bool      `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index:idx_login_event_models_user_created,priority:2,sort:desc"`
+}
+
+// リポジトリの構造体
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+// リポジトリを作成する関数
+func NewLoginEventRepository(db *gorm.DB) domain.LoginEventRepository {
+	return &loginEventRepository{
+		db: db,
+	}
+}
+
+// ドメインモデルをDBモデルに変換
+func toLoginEventModel(event *domain.LoginEvent) *LoginEventModel {
+	model := &LoginEventModel{
+		ID:        event.ID,
+		UserID:    event.UserID,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		DeviceID:  event.DeviceID,
+		Flags:     strings.Join(event.Flags, ","),
+		Succeeded: event.Succeeded,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.Location != nil {
+		model.Country = event.Location.Country
+		model.City = event.Location.City
+		model.Latitude = &event.Location.Latitude
+		model.Longitude = &event.Location.Longitude
+	}
+	return model
+}
+
+// DBモデルをドメインモデルに変換
+func toLoginEventDomain(model *LoginEventModel) *domain.LoginEvent {
+	event := &domain.LoginEvent{
+		ID:        model.ID,
+		UserID:    model.UserID,
+		IPAddress: model.IPAddress,
+		UserAgent: model.UserAgent,
+		DeviceID:  model.DeviceID,
+		Succeeded: model.Succeeded,
+		CreatedAt: model.CreatedAt,
+	}
+	if model.Flags != "" {
+		event.Flags = strings.Split(model.Flags, ",")
+	}
+	if model.Latitude != nil && model.Longitude != nil {
+		event.Location = &domain.GeoLocation{
+			Country:   model.Country,
+			City:      model.City,
+			Latitude:  *model.Latitude,
+			Longitude: *model.Longitude,
+		}
+	}
+	return event
+}
+
+// ログインイベントの記録
+func (r *loginEventRepository) Create(ctx context.Context, event *domain.LoginEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+
+	return translateError(conn(ctx, r.db).Create(toLoginEventModel(event)).Error)
+}
+
+// ユーザーの直近の完了したログインイベントを取得
+func (r *loginEventRepository) FindRecentSucceededByUserID(ctx context.Context, userID string, limit int) ([]*domain.LoginEvent, error) {
+	var models []LoginEventModel
+	result := conn(ctx, r.db).
+		Where("user_id = ? AND succeeded = ?", userID, true).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	events := make([]*domain.LoginEvent, 0, len(models))
+	for i := range models {
+		events = append(events, toLoginEventDomain(&models[i]))
+	}
+	return events, nil
+}
+
+// 本人確認が済んだログインイベントを完了扱いにする
+func (r *loginEventRepository) MarkSucceeded(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).
+		Model(&LoginEventModel{}).
+		Where("id = ?", id).
+		Update("succeeded", true)
+	return translateError(result.Error)
+}
@@ -0,0 +1,298 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+	"github.com/google/uuid"
+)
+
+// メモリ上のユーザーレコード
+type memoryUserRecord struct {
+	user      domain.User
+	deletedAt *time.Time
+}
+
+// メモリ上のリポジトリ（テスト・ローカル実行用）
+// 一意制約（論理削除済みのユーザーも含む）、論理削除、バージョン管理はPostgreSQL版と同じ振る舞いをする
+type memoryUserRepository struct {
+	mu      sync.RWMutex
+	records map[string]*memoryUserRecord
+}
+
+// リポジトリを作成する関数
+func NewMemoryUserRepository() domain.UserRepository {
+	return &memoryUserRepository{
+		records: make(map[string]*memoryUserRecord),
+	}
+}
+
+// ユーザーの作成
+func (r *memoryUserRepository) Create(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if _, exists := r.records[user.ID]; exists {
+		return domain.ErrConflict
+	}
+	// 論理削除済みのユーザーもメールアドレスを保持し続ける（一意インデックスと同じ）
+	for _, record := range r.records {
+		if record.user.Email == user.Email {
+			return domain.ErrEmailAlreadyExists
+		}
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	user.Version = 1
+
+	r.records[user.ID] = &memoryUserRecord{user: *user}
+	return nil
+}
+
+// IDでユーザーを検索
+func (r *memoryUserRepository) FindByID(_ context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.records[id]
+	if !ok || record.deletedAt != nil {
+		return nil, nil
+	}
+	user := record.user
+	return &user, nil
+}
+
+// 複数のIDでユーザーを検索
+func (r *memoryUserRepository) FindByIDs(_ context.Context, ids []string) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*domain.User
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		record, ok := r.records[id]
+		if !ok || record.deletedAt != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		user := record.user
+		users = append(users, &user)
+	}
+	return users, nil
+}
+
+// メールアドレスでユーザーを検索
+func (r *memoryUserRepository) FindByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, record := range r.records {
+		if record.user.Email == email && record.deletedAt == nil {
+			user := record.user
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// ユーザー情報の更新（読み込み時のバージョンと一致する場合のみ更新する）
+func (r *memoryUserRepository) Update(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[user.ID]
+	if !ok || record.deletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	if record.user.Version != user.Version {
+		return domain.ErrConcurrentModification
+	}
+	for id, other := range r.records {
+		if id != user.ID && other.user.Email == user.Email {
+			return domain.ErrEmailAlreadyExists
+		}
+	}
+
+	record.user.Email = user.Email
+	record.user.Password = user.Password
+	record.user.Name = user.Name
+	record.user.Locale = user.Locale
+	record.user.UpdatedAt = user.UpdatedAt
+	record.user.Version++
+
+	user.Version = record.user.Version
+	return nil
+}
+
+// ユーザーの削除（論理削除）
+func (r *memoryUserRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[id]; ok && record.deletedAt == nil {
+		now := time.Now()
+		record.deletedAt = &now
+	}
+	return nil
+}
+
+// ユーザーの一覧取得
+func (r *memoryUserRepository) List(_ context.Context, params domain.UserListParams) ([]*domain.User, error) {
+	if _, ok := userListColumns[params.SortField]; !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", query.ErrInvalidSort, params.SortField)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// 1. 絞り込み
+	var users []*domain.User
+	for _, record := range r.records {
+		if record.deletedAt != nil {
+			continue
+		}
+		matched, err := matchUser(&record.user, params.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			user := record.user
+			users = append(users, &user)
+		}
+	}
+
+	// 2. 並び順
+	desc := params.Desc
+	less := func(a, b *domain.User) bool {
+		c := compareUserField(a, b, params.SortField)
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(users, func(i, j int) bool { return less(users[i], users[j]) })
+
+	// 3. カーソルより後ろの要素
+	if params.After != nil {
+		boundary, err := cursorUser(params.SortField, params.After)
+		if err != nil {
+			return nil, err
+		}
+		start := sort.Search(len(users), func(i int) bool { return less(boundary, users[i]) })
+		users = users[start:]
+	}
+
+	if len(users) > params.Limit {
+		users = users[:params.Limit]
+	}
+	return users, nil
+}
+
+// カーソルの位置にあたるユーザー（比較用）
+func cursorUser(field string, cursor *domain.UserListPosition) (*domain.User, error) {
+	user := &domain.User{ID: cursor.ID}
+	switch field {
+	case domain.UserFieldEmail:
+		user.Email = cursor.Value
+	case domain.UserFieldName:
+		user.Name = cursor.Value
+	default:
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, query.ErrInvalidCursor
+		}
+		user.CreatedAt = t
+	}
+	return user, nil
+}
+
+// 指定したフィールドの比較（a < b なら負の値）
+func compareUserField(a, b *domain.User, field string) int {
+	switch field {
+	case domain.UserFieldEmail:
+		return strings.Compare(a.Email, b.Email)
+	case domain.UserFieldName:
+		return strings.Compare(a.Name, b.Name)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+// 絞り込み条件をすべて満たすか
+func matchUser(user *domain.User, conditions []domain.UserFilter) (bool, error) {
+	for _, c := range conditions {
+		var cmp int
+		switch c.Field {
+		case domain.UserFieldEmail, domain.UserFieldName:
+			actual := user.Email
+			if c.Field == domain.UserFieldName {
+				actual = user.Name
+			}
+			expected, ok := c.Value.(string)
+			if !ok {
+				return false, fmt.Errorf("%w: %s requires a string value", query.ErrInvalidFilter, c.Field)
+			}
+			switch c.Operator {
+			case domain.UserFilterContains:
+				if !strings.Contains(strings.ToLower(actual), strings.ToLower(expected)) {
+					return false, nil
+				}
+				continue
+			case domain.UserFilterPrefix:
+				if !strings.HasPrefix(strings.ToLower(actual), strings.ToLower(expected)) {
+					return false, nil
+				}
+				continue
+			}
+			cmp = strings.Compare(actual, expected)
+		case domain.UserFieldCreatedAt:
+			expected, ok := c.Value.(time.Time)
+			if !ok {
+				return false, fmt.Errorf("%w: %s requires a time value", query.ErrInvalidFilter, c.Field)
+			}
+			cmp = user.CreatedAt.Compare(expected)
+		default:
+			return false, fmt.Errorf("%w: unknown field %q", query.ErrInvalidFilter, c.Field)
+		}
+
+		var matched bool
+		switch c.Operator {
+		case domain.UserFilterEq:
+			matched = cmp == 0
+		case domain.UserFilterNe:
+			matched = cmp != 0
+		case domain.UserFilterLt:
+			matched = cmp < 0
+		case domain.UserFilterLte:
+			matched = cmp <= 0
+		case domain.UserFilterGt:
+			matched = cmp > 0
+		case domain.UserFilterGte:
+			matched = cmp >= 0
+		default:
+			return false, fmt.Errorf("%w: unsupported operator %q", query.ErrInvalidFilter, c.Operator)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// アウトボックスのテーブル構造
+type OutboxMessageModel struct {
+	ID            string    `gorm:"primaryKey;type:uuid"`
+	EventType     string    `gorm:"not null"`
+	AggregateID   string    `gorm:"not null"`
+	Payload       []byte    `gorm:"type:jsonb;not null"`
+	OccurredAt    time.Time `gorm:"not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	LastError     string
+	NextAttemptAt time.Time `gorm:"not null"`
+	PublishedAt   *time.Time
+	CreatedAt     time.Time `gorm:"not null"`
+}
+
+// リポジトリの構造体
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// リポジトリを作成する関数
+func NewOutboxRepository(db *gorm.DB) domain.OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// DBモデルをドメインモデルに変換
+func toOutboxDomain(model *OutboxMessageModel) *domain.OutboxMessage {
+	return &domain.OutboxMessage{
+		ID:            model.ID,
+		EventType:     model.EventType,
+		AggregateID:   model.AggregateID,
+		Payload:       model.Payload,
+		OccurredAt:    model.OccurredAt,
+		Attempts:      model.Attempts,
+		LastError:     model.LastError,
+		NextAttemptAt: model.NextAttemptAt,
+		PublishedAt:   model.PublishedAt,
+	}
+}
+
+// イベントの保存
+func (r *outboxRepository) Add(ctx context.Context, event domain.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventType(), err)
+	}
+
+	now := time.Now()
+	model := &OutboxMessageModel{
+		ID:            uuid.New().String(),
+		EventType:     event.EventType(),
+		AggregateID:   event.AggregateID(),
+		Payload:       payload,
+		OccurredAt:    event.OccurredAt(),
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	return translateError(conn(ctx, r.db).Create(model).Error)
+}
+
+// 送信対象のメッセージを取得
+// 他のワーカーがロック中の行は読み飛ばすため、複数のレプリカで並行して処理できる
+func (r *outboxRepository) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxMessage, error) {
+	var models []OutboxMessageModel
+	result := conn(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Order("occurred_at").
+		Limit(limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	messages := make([]*domain.OutboxMessage, 0, len(models))
+	for i := range models {
+		messages = append(messages, toOutboxDomain(&models[i]))
+	}
+	return messages, nil
+}
+
+// 送信済みにする
+func (r *outboxRepository) MarkPublished(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).
+		Model(&OutboxMessageModel{}).
+		Where("id = ?", id).
+		Update("published_at", time.Now())
+	return translateError(result.Error)
+}
+
+// 送信失敗を記録し、次回の送信時刻を設定する
+func (r *outboxRepository) MarkFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) error {
+	result := conn(ctx, r.db).
+		Model(&OutboxMessageModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      lastError,
+			"next_attempt_at": nextAttemptAt,
+		})
+	return translateError(result.Error)
+}
@@ -0,0 +1,26 @@
+package persistence
+
+import (
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"gorm.io/gorm"
+)
+
+// SQLiteを使うリポジトリを作成する関数（テスト・ローカル実行用）
+// クエリはPostgreSQL版と共通で、テーブルはAutoMigrateで作成する
+func NewSQLiteUserRepository(db *gorm.DB) (domain.UserRepository, error) {
+	if err := db.AutoMigrate(&UserModel{}); err != nil {
+		return nil, err
+	}
+	return NewUserRepository(db), nil
+}
+
+// すべてのテーブルをAutoMigrateで作成する関数
+// SQLite向けで、PostgreSQLではバージョン付きマイグレーションを使うこと
+func AutoMigrateModels(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&UserModel{},
+		&LoginEventModel{},
+		&LoginChallengeModel{},
+		&OutboxMessageModel{},
+	)
+}
@@ -0,0 +1,520 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/encryption"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// すべてのテーブルを作成したSQLiteのインメモリDB
+func newSQLiteTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.NewSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	if err := AutoMigrateModels(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, repo domain.UserRepository, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{Email: email, Password: "hashed-password", Name: "Taro Yamada"}
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return user
+}
+
+func TestLoginEventRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	user := createTestUser(t, NewUserRepository(db), "taro@example.com")
+	repo := NewLoginEventRepository(db)
+
+	base := time.Now().UTC().Truncate(time.Second)
+	events := []*domain.LoginEvent{
+		{UserID: user.ID, IPAddress: "192.0.2.1", UserAgent: "laptop", DeviceID: "a", Succeeded: true, CreatedAt: base},
+		{
+			UserID: user.ID, IPAddress: "198.51.100.1", UserAgent: "phone", DeviceID: "b", Succeeded: true, CreatedAt: base.Add(time.Minute),
+			Location: &domain.GeoLocation{Country: "JP", City: "Tokyo", Latitude: 35.68, Longitude: 139.69},
+			Flags:    []string{domain.LoginFlagNewDevice, domain.LoginFlagImpossibleTravel},
+		},
+		{UserID: user.ID, IPAddress: "192.0.2.1", UserAgent: "laptop", DeviceID: "a", Succeeded: true, CreatedAt: base.Add(2 * time.Minute)},
+		// MFAを要求して完了しなかったログインは履歴に含めない
+		{UserID: user.ID, IPAddress: "203.0.113.1", UserAgent: "tablet", DeviceID: "c", CreatedAt: base.Add(3 * time.Minute), Flags: []string{domain.LoginFlagNewDevice}},
+	}
+	for _, event := range events {
+		if err := repo.Create(ctx, event); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if event.ID == "" {
+			t.Error("Create() did not assign an ID")
+		}
+	}
+
+	got, err := repo.FindRecentSucceededByUserID(ctx, user.ID, 2)
+	if err != nil {
+		t.Fatalf("FindRecentSucceededByUserID() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].ID != events[2].ID || got[1].ID != events[1].ID {
+		t.Errorf("events are not ordered newest first: %s, %s", got[0].ID, got[1].ID)
+	}
+
+	flagged := got[1]
+	if !flagged.HasFlag(domain.LoginFlagNewDevice) || !flagged.HasFlag(domain.LoginFlagImpossibleTravel) {
+		t.Errorf("Flags = %v", flagged.Flags)
+	}
+	if flagged.Location == nil || flagged.Location.City != "Tokyo" || flagged.Location.Latitude != 35.68 {
+		t.Errorf("Location = %+v", flagged.Location)
+	}
+	if got[0].Location != nil || got[0].Flags != nil {
+		t.Errorf("unexpected location or flags: %+v", got[0])
+	}
+
+	// 本人確認が済んだログインは履歴に含める
+	if err := repo.MarkSucceeded(ctx, events[3].ID); err != nil {
+		t.Fatalf("MarkSucceeded() error = %v", err)
+	}
+	got, err = repo.FindRecentSucceededByUserID(ctx, user.ID, 1)
+	if err != nil {
+		t.Fatalf("FindRecentSucceededByUserID() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != events[3].ID || !got[0].Succeeded {
+		t.Errorf("verified login is not in history: %+v", got)
+	}
+}
+
+func TestLoginChallengeRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	user := createTestUser(t, NewUserRepository(db), "taro@example.com")
+	events := NewLoginEventRepository(db)
+	repo := NewLoginChallengeRepository(db)
+
+	got, err := repo.FindLatestByUserID(ctx, user.ID)
+	if err != nil || got != nil {
+		t.Fatalf("FindLatestByUserID() = %+v, %v, want nil", got, err)
+	}
+
+	base := time.Now().UTC().Truncate(time.Second)
+	var challenges []*domain.LoginChallenge
+	for i, hash := range []string{"first", "second"} {
+		event := &domain.LoginEvent{UserID: user.ID, IPAddress: "192.0.2.1", UserAgent: "phone", DeviceID: "b", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := events.Create(ctx, event); err != nil {
+			t.Fatalf("Create(event) error = %v", err)
+		}
+		challenge := &domain.LoginChallenge{
+			UserID:       user.ID,
+			LoginEventID: event.ID,
+			CodeHash:     hash,
+			ExpiresAt:    event.CreatedAt.Add(10 * time.Minute),
+			CreatedAt:    event.CreatedAt,
+		}
+		if err := repo.Create(ctx, challenge); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		challenges = append(challenges, challenge)
+	}
+
+	// 新しい確認は以前の確認を置き換える
+	var count int64
+	if err := db.Model(&LoginChallengeModel{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("stored %d challenges, want 1", count)
+	}
+
+	if err := repo.IncrementAttempts(ctx, challenges[1].ID); err != nil {
+		t.Fatalf("IncrementAttempts() error = %v", err)
+	}
+	got, err = repo.FindLatestByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindLatestByUserID() error = %v", err)
+	}
+	if got == nil || got.ID != challenges[1].ID || got.CodeHash != "second" || got.Attempts != 1 || !got.ExpiresAt.Equal(challenges[1].ExpiresAt) {
+		t.Fatalf("FindLatestByUserID() = %+v", got)
+	}
+
+	if err := repo.Delete(ctx, got.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := repo.FindLatestByUserID(ctx, user.ID); err != nil || got != nil {
+		t.Errorf("FindLatestByUserID() after Delete = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	repo := NewOutboxRepository(db)
+
+	occurredAt := time.Now().Add(-time.Minute)
+	for _, event := range []domain.DomainEvent{
+		domain.UserRegistered{UserID: "user-1", RegisteredAt: occurredAt},
+		domain.UserDeleted{UserID: "user-1", DeletedAt: occurredAt.Add(time.Second)},
+	} {
+		if err := repo.Add(ctx, event); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchPending() error = %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("got %d pending messages, want 2", len(pending))
+	}
+	if pending[0].EventType != domain.EventUserRegistered || pending[1].EventType != domain.EventUserDeleted {
+		t.Errorf("messages are not ordered by occurrence: %s, %s", pending[0].EventType, pending[1].EventType)
+	}
+
+	if err := repo.MarkPublished(ctx, pending[0].ID); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+	if err := repo.MarkFailed(ctx, pending[1].ID, "broker unavailable", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+
+	// 送信済みと再試行待ちのメッセージは取得されない
+	if pending, err := repo.FetchPending(ctx, 10); err != nil || len(pending) != 0 {
+		t.Errorf("FetchPending() = %d messages, %v, want none", len(pending), err)
+	}
+
+	var model OutboxMessageModel
+	if err := db.First(&model, "id = ?", pending[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if model.Attempts != 1 || model.LastError != "broker unavailable" {
+		t.Errorf("failed message = %+v", model)
+	}
+}
+
+func TestTxManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commits on success", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		userRepo := NewUserRepository(db)
+		outboxRepo := NewOutboxRepository(db)
+
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			user := &domain.User{Email: "taro@example.com", Password: "hashed-password", Name: "Taro"}
+			if err := userRepo.Create(ctx, user); err != nil {
+				return err
+			}
+			return outboxRepo.Add(ctx, domain.UserRegistered{UserID: user.ID, RegisteredAt: time.Now()})
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction() error = %v", err)
+		}
+
+		if user, _ := userRepo.FindByEmail(ctx, "taro@example.com"); user == nil {
+			t.Error("user was not committed")
+		}
+		if pending, _ := outboxRepo.FetchPending(ctx, 10); len(pending) != 1 {
+			t.Errorf("got %d outbox messages, want 1", len(pending))
+		}
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		userRepo := NewUserRepository(db)
+		outboxRepo := NewOutboxRepository(db)
+		errAbort := errors.New("abort")
+
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			user := &domain.User{Email: "taro@example.com", Password: "hashed-password", Name: "Taro"}
+			if err := userRepo.Create(ctx, user); err != nil {
+				return err
+			}
+			if err := outboxRepo.Add(ctx, domain.UserRegistered{UserID: user.ID, RegisteredAt: time.Now()}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithinTransaction() error = %v, want %v", err, errAbort)
+		}
+
+		if user, _ := userRepo.FindByEmail(ctx, "taro@example.com"); user != nil {
+			t.Error("user was not rolled back")
+		}
+		if pending, _ := outboxRepo.FetchPending(ctx, 10); len(pending) != 0 {
+			t.Errorf("got %d outbox messages, want 0", len(pending))
+		}
+	})
+
+	t.Run("nested calls join the outer transaction", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		userRepo := NewUserRepository(db)
+		txManager := NewTxManager(db)
+		errAbort := errors.New("abort")
+
+		err := txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+				return userRepo.Create(ctx, &domain.User{Email: "taro@example.com", Password: "hashed-password", Name: "Taro"})
+			}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithinTransaction() error = %v, want %v", err, errAbort)
+		}
+		if user, _ := userRepo.FindByEmail(ctx, "taro@example.com"); user != nil {
+			t.Error("inner write was committed independently of the outer transaction")
+		}
+	})
+
+	// 再試行のたびに前回の書き込みが取り消されることも確認する
+	serializationFailure := &pgconn.PgError{Code: sqlStateSerializationFailure}
+
+	t.Run("retries serialization failures", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		userRepo := NewUserRepository(db)
+
+		attempts := 0
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			if err := userRepo.Create(ctx, &domain.User{Email: "taro@example.com", Password: "hashed-password", Name: "Taro"}); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return serializationFailure
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithinTransaction() error = %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("attempts = %d, want 3", attempts)
+		}
+		var count int64
+		db.Model(&UserModel{}).Count(&count)
+		if count != 1 {
+			t.Errorf("got %d users, want 1", count)
+		}
+	})
+
+	t.Run("gives up after the maximum number of retries", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+
+		attempts := 0
+		start := time.Now()
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return serializationFailure
+		})
+		if !errors.Is(err, domain.ErrSerializationFailure) {
+			t.Fatalf("WithinTransaction() error = %v, want %v", err, domain.ErrSerializationFailure)
+		}
+		if attempts != maxTxRetries+1 {
+			t.Errorf("attempts = %d, want %d", attempts, maxTxRetries+1)
+		}
+		// 指数バックオフで少なくとも基準値の 1+2+4 倍は待つ
+		if elapsed, want := time.Since(start), txRetryBaseDelay*7; elapsed < want {
+			t.Errorf("retried after %v, want at least %v", elapsed, want)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+
+		attempts := 0
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return &pgconn.PgError{Code: sqlStateUniqueViolation}
+		})
+		if !errors.Is(err, domain.ErrConflict) || attempts != 1 {
+			t.Errorf("error = %v, attempts = %d, want %v after 1 attempt", err, attempts, domain.ErrConflict)
+		}
+	})
+
+	t.Run("stops retrying when the context is canceled", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		ctx, cancel := context.WithCancel(ctx)
+
+		attempts := 0
+		err := NewTxManager(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			cancel()
+			return serializationFailure
+		})
+		if !errors.Is(err, context.Canceled) || attempts != 1 {
+			t.Errorf("error = %v, attempts = %d, want %v after 1 attempt", err, attempts, context.Canceled)
+		}
+	})
+}
+
+// テスト用の鍵ファイルから暗号化を作成する（keys の順に鍵の内容を変える）
+func newTestEncryptor(t *testing.T, current string, keys ...string) *encryption.FieldEncryptor {
+	t.Helper()
+	keyOf := func(b byte) string {
+		return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+	}
+
+	file := map[string]interface{}{
+		"current_key_id":  current,
+		"keys":            map[string]string{},
+		"blind_index_key": keyOf('i'),
+	}
+	for i, id := range keys {
+		file["keys"].(map[string]string)[id] = keyOf(byte('a' + i))
+	}
+	data, _ := json.Marshal(file)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	kms, err := encryption.NewLocalKMS(path)
+	if err != nil {
+		t.Fatalf("NewLocalKMS() error = %v", err)
+	}
+	return encryption.NewFieldEncryptor(kms, kms.BlindIndexKey())
+}
+
+func TestEncryptedUserRepository_StoresCiphertext(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	repo := NewEncryptedUserRepository(db, newTestEncryptor(t, "k1", "k1"))
+	user := createTestUser(t, repo, "taro@example.com")
+
+	var model UserModel
+	if err := db.First(&model, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	for column, value := range map[string]string{"email": model.Email, "email_index": model.EmailIndex, "name": model.Name} {
+		if strings.Contains(value, "taro") || strings.Contains(value, "Taro") {
+			t.Errorf("%s is stored in plain text: %s", column, value)
+		}
+	}
+	if !encryption.IsEncrypted(model.Email) || !encryption.IsEncrypted(model.Name) {
+		t.Errorf("stored values are not encrypted: %+v", model)
+	}
+
+	got, err := repo.FindByEmail(ctx, "taro@example.com")
+	if err != nil || got == nil || got.ID != user.ID || got.Name != "Taro Yamada" {
+		t.Errorf("FindByEmail() = %+v, %v", got, err)
+	}
+
+	// 暗号文を別のユーザーの行にコピーしても復号できない
+	other := createTestUser(t, repo, "hanako@example.com")
+	if err := db.Model(&UserModel{}).Where("id = ?", other.ID).Update("name", model.Name).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindByID(ctx, other.ID); !errors.Is(err, encryption.ErrDecryptionFailed) {
+		t.Errorf("FindByID() with copied ciphertext error = %v, want %v", err, encryption.ErrDecryptionFailed)
+	}
+}
+
+func TestEncryptedUserRepository_LegacyEmailIndex(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+
+	// 暗号化導入前の行（再暗号化ジョブの実行前）
+	legacy := createTestUser(t, NewUserRepository(db), "legacy@example.com")
+	repo := NewEncryptedUserRepository(db, newTestEncryptor(t, "k1", "k1"))
+
+	got, err := repo.FindByEmail(ctx, "legacy@example.com")
+	if err != nil || got == nil || got.ID != legacy.ID {
+		t.Errorf("FindByEmail() = %+v, %v, want %s", got, err, legacy.ID)
+	}
+
+	err = repo.Create(ctx, &domain.User{Email: "legacy@example.com", Password: "hashed", Name: "Taro"})
+	if !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Errorf("Create() with a legacy email error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+	}
+
+	other := createTestUser(t, repo, "hanako@example.com")
+	other.Email = "legacy@example.com"
+	if err := repo.Update(ctx, other); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Errorf("Update() to a legacy email error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+	}
+
+	// 本人の更新は重複にならない
+	if err := repo.Update(ctx, got); err != nil {
+		t.Errorf("Update() of the legacy user error = %v", err)
+	}
+}
+
+func TestUserReencryptor(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+
+	// 暗号化導入前の平文の行
+	plainUser := createTestUser(t, NewUserRepository(db), "legacy@example.com")
+
+	// 古い鍵で暗号化した行（論理削除済みを含む）
+	oldRepo := NewEncryptedUserRepository(db, newTestEncryptor(t, "k1", "k1"))
+	oldUser := createTestUser(t, oldRepo, "taro@example.com")
+	deletedUser := createTestUser(t, oldRepo, "deleted@example.com")
+	if err := oldRepo.Delete(ctx, deletedUser.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新しい鍵に切り替え
+	encryptor := newTestEncryptor(t, "k2", "k1", "k2")
+	repo := NewEncryptedUserRepository(db, encryptor)
+	newUser := createTestUser(t, repo, "hanako@example.com")
+
+	result, err := NewUserReencryptor(db, encryptor, 2).Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Scanned != 4 || result.Updated != 3 || result.Skipped != 0 {
+		t.Errorf("Run() = %+v, want 4 scanned, 3 updated", result)
+	}
+
+	var models []UserModel
+	if err := db.Unscoped().Find(&models).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range models {
+		if encryptor.NeedsReencryption(m.Email) || encryptor.NeedsReencryption(m.Name) {
+			t.Errorf("user %s is not encrypted with the current key", m.ID)
+		}
+		if m.Version != 1 {
+			t.Errorf("user %s version = %d, want 1", m.ID, m.Version)
+		}
+	}
+
+	// 古い鍵を削除しても全員を検索・復号できる
+	retired := NewEncryptedUserRepository(db, newTestEncryptor(t, "k2", "k0", "k2"))
+	for _, u := range []*domain.User{plainUser, oldUser, newUser} {
+		got, err := retired.FindByEmail(ctx, u.Email)
+		if err != nil || got == nil || got.ID != u.ID {
+			t.Errorf("FindByEmail(%s) = %+v, %v", u.Email, got, err)
+		}
+	}
+
+	if result, err := NewUserReencryptor(db, encryptor, 2).Run(ctx); err != nil || result.Updated != 0 {
+		t.Errorf("second Run() = %+v, %v, want nothing to update", result, err)
+	}
+}
@@ -0,0 +1,67 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"gorm.io/gorm"
+)
+
+// シリアライゼーション失敗・デッドロック時の再試行回数
+const maxTxRetries = 3
+
+// 再試行の待機時間の基準値
+const txRetryBaseDelay = 20 * time.Millisecond
+
+type txKey struct{}
+
+// トランザクションマネージャーの構造体
+type txManager struct {
+	db *gorm.DB
+}
+
+// トランザクションマネージャーを作成する関数
+func NewTxManager(db *gorm.DB) domain.TxManager {
+	return &txManager{
+		db: db,
+	}
+}
+
+// fn をトランザクション内で実行する
+// 既にトランザクション内の場合はそのトランザクションに参加する
+func (m *txManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		err = translateError(err)
+
+		if !errors.Is(err, domain.ErrSerializationFailure) || attempt >= maxTxRetries {
+			return err
+		}
+
+		// 指数バックオフ（ジッター付き）で再試行
+		delay := txRetryBaseDelay << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// コンテキストにトランザクションがあればそれを、なければ通常の接続を返す
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/encryption"
+	"gorm.io/gorm"
+)
+
+// 再暗号化の結果
+type ReencryptionResult struct {
+	// 確認した行数
+	Scanned int
+	// 暗号化し直した行数
+	Updated int
+	// 処理中に他の更新と競合した行数（更新時に現在の鍵で暗号化されるため再処理は不要）
+	Skipped int
+}
+
+// 個人情報の列を現在の鍵で暗号化し直すジョブ
+// 鍵のローテーション後や、暗号化導入前の平文の行を暗号化するときに実行する
+type UserReencryptor struct {
+	repo      *userRepository
+	batchSize int
+}
+
+// 再暗号化ジョブを作成する関数
+func NewUserReencryptor(db *gorm.DB, encryptor *encryption.FieldEncryptor, batchSize int) *UserReencryptor {
+	return &UserReencryptor{
+		repo:      &userRepository{db: db, encryptor: encryptor},
+		batchSize: batchSize,
+	}
+}
+
+// 論理削除済みの行も含め、すべての行を処理する
+func (j *UserReencryptor) Run(ctx context.Context) (ReencryptionResult, error) {
+	var result ReencryptionResult
+	lastID := ""
+
+	for {
+		// 1. IDの順に1バッチ分を取得
+		var models []UserModel
+		err := j.repo.db.WithContext(ctx).
+			Unscoped().
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(j.batchSize).
+			Find(&models).Error
+		if err != nil {
+			return result, translateError(err)
+		}
+		if len(models) == 0 {
+			return result, nil
+		}
+		lastID = models[len(models)-1].ID
+
+		for i := range models {
+			result.Scanned++
+			outcome, err := j.reencrypt(ctx, &models[i])
+			if err != nil {
+				return result, err
+			}
+			switch outcome {
+			case reencryptUpdated:
+				result.Updated++
+			case reencryptConflict:
+				result.Skipped++
+			}
+		}
+	}
+}
+
+// 1行の再暗号化の結果
+type reencryptOutcome int
+
+const (
+	reencryptUnchanged reencryptOutcome = iota
+	reencryptUpdated
+	reencryptConflict
+)
+
+// 1行を暗号化し直す
+func (j *UserReencryptor) reencrypt(ctx context.Context, model *UserModel) (reencryptOutcome, error) {
+	encryptor := j.repo.encryptor
+
+	// 2. 古い鍵で復号
+	user, err := j.repo.toDomain(ctx, model)
+	if err != nil {
+		return reencryptUnchanged, err
+	}
+	if !encryptor.NeedsReencryption(model.Email) &&
+		!encryptor.NeedsReencryption(model.Name) &&
+		model.EmailIndex == j.repo.emailIndex(user.Email) {
+		return reencryptUnchanged, nil
+	}
+
+	// 3. 現在の鍵で暗号化
+	reencrypted, err := j.repo.toModel(ctx, user)
+	if err != nil {
+		return reencryptUnchanged, err
+	}
+
+	// 4. 読み込み後に更新されていない場合のみ保存する（更新日時とバージョンは変えない）
+	res := j.repo.db.WithContext(ctx).
+		Unscoped().
+		Model(&UserModel{}).
+		Where("id = ? AND version = ?", model.ID, model.Version).
+		UpdateColumns(map[string]interface{}{
+			"email":       reencrypted.Email,
+			"email_index": reencrypted.EmailIndex,
+			"name":        reencrypted.Name,
+		})
+	if res.Error != nil {
+		return reencryptUnchanged, translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return reencryptConflict, nil
+	}
+	return reencryptUpdated, nil
+}
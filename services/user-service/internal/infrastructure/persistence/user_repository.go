@@ -2,61 +2,164 @@ package persistence
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/encryption"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // データベースのテーブル構造
+// 暗号化を有効にした場合、Email と Name には暗号文が保存される
 type UserModel struct {
-	ID        string `gorm:"primaryKey;type:uuid"`
-	Email     string `gorm:"uniqueIndex;not null"`
-	Password  string `gorm:"not null"`
-	Name      string `gorm:"not null"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID    string `gorm:"primaryKey;type:uuid"`
+	Email string `gorm:"not null"`
+	// メールアドレスの検索・一意制約に使うブラインドインデックス（暗号化しない場合は平文）
+	EmailIndex string `gorm:"uniqueIndex;not null"`
+	Password   string `gorm:"not null"`
+	Name       string `gorm:"not null"`
+	Locale     string `gorm:"not null;default:''"`
+	Version    int    `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
 }
 
+// 暗号化する列（追加認証データに使う）
+const (
+	userEmailColumn = "user_models.email"
+	userNameColumn  = "user_models.name"
+)
+
 // リポジトリの構造体
 type userRepository struct {
 	db *gorm.DB
+	// nil の場合は暗号化しない
+	encryptor *encryption.FieldEncryptor
 }
 
 // リポジトリを作成する関数
 func NewUserRepository(db *gorm.DB) domain.UserRepository {
-	// テーブルの自動作成（本番環境では手動マイグレーションを推奨）
-	db.AutoMigrate(&UserModel{})
-
 	return &userRepository{
 		db: db,
 	}
 }
 
-// ドメインモデルをDBモデルに変換
-func toModel(user *domain.User) *UserModel {
-	return &UserModel{
-		ID:        user.ID,
-		Email:     user.Email,
-		Password:  user.Password,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+// 個人情報の列を暗号化するリポジトリを作成する関数
+func NewEncryptedUserRepository(db *gorm.DB, encryptor *encryption.FieldEncryptor) domain.UserRepository {
+	return &userRepository{
+		db:        db,
+		encryptor: encryptor,
 	}
 }
 
-// DBモデルをドメインモデルに変換
-func toDomain(model *UserModel) *domain.User {
+// ドメインモデルをDBモデルに変換（個人情報の列は暗号化する）
+func (r *userRepository) toModel(ctx context.Context, user *domain.User) (*UserModel, error) {
+	email, err := r.encrypt(ctx, user.Email, userEmailColumn, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.encrypt(ctx, user.Name, userNameColumn, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserModel{
+		ID:         user.ID,
+		Email:      email,
+		EmailIndex: r.emailIndex(user.Email),
+		Password:   user.Password,
+		Name:       name,
+		Locale:     user.Locale,
+		Version:    user.Version,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+	}, nil
+}
+
+// DBモデルをドメインモデルに変換（個人情報の列は復号する）
+func (r *userRepository) toDomain(ctx context.Context, model *UserModel) (*domain.User, error) {
+	email, err := r.decrypt(ctx, model.Email, userEmailColumn, model.ID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.decrypt(ctx, model.Name, userNameColumn, model.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &domain.User{
 		ID:        model.ID,
-		Email:     model.Email,
+		Email:     email,
 		Password:  model.Password,
-		Name:      model.Name,
+		Name:      name,
+		Locale:    model.Locale,
+		Version:   model.Version,
 		CreatedAt: model.CreatedAt,
 		UpdatedAt: model.UpdatedAt,
+	}, nil
+}
+
+func (r *userRepository) encrypt(ctx context.Context, value, column, id string) (string, error) {
+	if r.encryptor == nil {
+		return value, nil
 	}
+	return r.encryptor.Encrypt(ctx, value, column+":"+id)
+}
+
+func (r *userRepository) decrypt(ctx context.Context, value, column, id string) (string, error) {
+	if r.encryptor == nil {
+		return value, nil
+	}
+	plaintext, err := r.encryptor.Decrypt(ctx, value, column+":"+id)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s of user %s: %w", column, id, err)
+	}
+	return plaintext, nil
+}
+
+// メールアドレスの検索キー
+func (r *userRepository) emailIndex(email string) string {
+	if r.encryptor == nil {
+		return email
+	}
+	return r.encryptor.BlindIndex(email)
+}
+
+// メールアドレスで検索するときの検索キー
+// 暗号化を有効にしてから再暗号化ジョブを実行するまで、既存の行は平文のメールアドレスを
+// インデックスとしているため、ブラインドインデックスと平文の両方で検索する
+func (r *userRepository) emailIndexes(email string) []string {
+	if r.encryptor == nil {
+		return []string{email}
+	}
+	return []string{r.encryptor.BlindIndex(email), email}
+}
+
+// 平文のインデックスの行とのメールアドレスの重複を確認する
+// インデックスの値が異なるため一意制約では検出できない（論理削除済みの行も一意制約の対象）
+func (r *userRepository) checkLegacyEmailIndex(ctx context.Context, user *domain.User) error {
+	if r.encryptor == nil {
+		return nil
+	}
+	var count int64
+	err := conn(ctx, r.db).
+		Unscoped().
+		Model(&UserModel{}).
+		Where("email_index = ? AND id <> ?", user.Email, user.ID).
+		Count(&count).Error
+	if err != nil {
+		return translateError(err)
+	}
+	if count > 0 {
+		return domain.ErrEmailAlreadyExists
+	}
+	return nil
 }
 
 // ユーザーの作成
@@ -65,15 +168,19 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	if user.ID == "" {
 		user.ID = uuid.New().String()
 	}
+	user.Version = 1
 
-	model := toModel(user)
-	result := r.db.WithContext(ctx).Create(model)
+	if err := r.checkLegacyEmailIndex(ctx, user); err != nil {
+		return err
+	}
+	model, err := r.toModel(ctx, user)
+	if err != nil {
+		return err
+	}
+	result := conn(ctx, r.db).Create(model)
 	if result.Error != nil {
-		// メールアドレスの重複エラーをドメインエラーに変換
-		if isDuplicateKeyError(result.Error) {
-			return domain.ErrEmailAlreadyExists
-		}
-		return result.Error
+		// メールアドレスの重複は一意制約違反として domain.ErrEmailAlreadyExists に変換される
+		return translateError(result.Error)
 	}
 
 	// 生成されたIDを元のユーザーオブジェクトに反映
@@ -84,51 +191,222 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 // メールアドレスでユーザーを検索
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var model UserModel
-	result := r.db.WithContext(ctx).Where("email = ?", email).First(&model)
+	result := conn(ctx, r.db).Where("email_index IN ?", r.emailIndexes(email)).First(&model)
 	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		return nil, result.Error
+		return nil, translateError(result.Error)
 	}
-	return toDomain(&model), nil
+	return r.toDomain(ctx, &model)
 }
 
 // IDでユーザーを検索
 func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
 	var model UserModel
-	result := r.db.WithContext(ctx).First(&model, "id = ?", id)
+	result := conn(ctx, r.db).First(&model, "id = ?", id)
 	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		return nil, result.Error
+		return nil, translateError(result.Error)
 	}
-	return toDomain(&model), nil
+	return r.toDomain(ctx, &model)
 }
 
-// ユーザー情報の更新
+// 複数のIDでユーザーを検索
+func (r *userRepository) FindByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var models []UserModel
+	if err := conn(ctx, r.db).Where("id IN ?", ids).Find(&models).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	users := make([]*domain.User, 0, len(models))
+	for i := range models {
+		user, err := r.toDomain(ctx, &models[i])
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ユーザー情報の更新（読み込み時のバージョンと一致する場合のみ更新する）
 func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
-	model := toModel(user)
-	result := r.db.WithContext(ctx).Save(model)
+	if err := r.checkLegacyEmailIndex(ctx, user); err != nil {
+		return err
+	}
+	model, err := r.toModel(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	result := conn(ctx, r.db).
+		Model(&UserModel{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"email":       model.Email,
+			"email_index": model.EmailIndex,
+			"password":    model.Password,
+			"name":        model.Name,
+			"locale":      model.Locale,
+			"updated_at":  model.UpdatedAt,
+			"version":     gorm.Expr("version + 1"),
+		})
 	if result.Error != nil {
-		if isDuplicateKeyError(result.Error) {
-			return domain.ErrEmailAlreadyExists
+		return translateError(result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		// 存在しないのか、他の更新と競合したのかを判別する
+		var count int64
+		if err := conn(ctx, r.db).Model(&UserModel{}).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+			return translateError(err)
+		}
+		if count == 0 {
+			return domain.ErrUserNotFound
 		}
-		return result.Error
+		return domain.ErrConcurrentModification
 	}
+
+	user.Version++
 	return nil
 }
 
 // ユーザーの削除
 func (r *userRepository) Delete(ctx context.Context, id string) error {
-	result := r.db.WithContext(ctx).Delete(&UserModel{}, "id = ?", id)
-	return result.Error
+	result := conn(ctx, r.db).Delete(&UserModel{}, "id = ?", id)
+	return translateError(result.Error)
+}
+
+// 一覧の並び順・絞り込みに使えるフィールドと列の対応
+var userListColumns = map[string]string{
+	domain.UserFieldEmail:     "email",
+	domain.UserFieldName:      "name",
+	domain.UserFieldCreatedAt: "created_at",
+}
+
+// ユーザーの一覧取得
+func (r *userRepository) List(ctx context.Context, params domain.UserListParams) ([]*domain.User, error) {
+	column, ok := userListColumns[params.SortField]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", query.ErrInvalidSort, params.SortField)
+	}
+	// 暗号文の順序には意味がないため、暗号化した列では並べ替えられない
+	if r.encryptor != nil && column != "created_at" {
+		return nil, fmt.Errorf("%w: cannot sort by encrypted field %q", query.ErrInvalidSort, params.SortField)
+	}
+
+	db := conn(ctx, r.db).Model(&UserModel{})
+
+	// 1. 絞り込み条件
+	for _, c := range params.Filters {
+		filterColumn, ok := userListColumns[c.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", query.ErrInvalidFilter, c.Field)
+		}
+		if r.encryptor != nil && filterColumn != "created_at" {
+			// 暗号化した列はブラインドインデックスによるメールアドレスの完全一致のみ検索できる
+			if c.Field != domain.UserFieldEmail || c.Operator != domain.UserFilterEq {
+				return nil, fmt.Errorf("%w: only exact match on email is supported for encrypted fields", query.ErrInvalidFilter)
+			}
+			email, _ := c.Value.(string)
+			db = db.Where("email_index IN ?", r.emailIndexes(email))
+			continue
+		}
+		expr, value, err := filterExpr(filterColumn, c)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(expr, value)
+	}
+
+	// 2. 並び順
+	order, cmp := "ASC", ">"
+	if params.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	// 3. カーソルより後ろの要素
+	if params.After != nil {
+		value, err := userSortValue(params.SortField, params.After.Value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", column, cmp, column, cmp),
+			value, value, params.After.ID,
+		)
+	}
+
+	var models []UserModel
+	result := db.
+		Order(fmt.Sprintf("%s %s, id %s", column, order, order)).
+		Limit(params.Limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+
+	users := make([]*domain.User, 0, len(models))
+	for i := range models {
+		user, err := r.toDomain(ctx, &models[i])
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// カーソルに保存された並び順キーの値を列の型に変換
+func userSortValue(field, value string) (interface{}, error) {
+	if field != domain.UserFieldCreatedAt {
+		return value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, query.ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// 絞り込み条件のSQL式
+// 部分一致・前方一致はデータベースによらず大文字小文字を区別しない
+func filterExpr(column string, c domain.UserFilter) (string, interface{}, error) {
+	switch c.Operator {
+	case domain.UserFilterEq:
+		return column + " = ?", c.Value, nil
+	case domain.UserFilterNe:
+		return column + " <> ?", c.Value, nil
+	case domain.UserFilterLt:
+		return column + " < ?", c.Value, nil
+	case domain.UserFilterLte:
+		return column + " <= ?", c.Value, nil
+	case domain.UserFilterGt:
+		return column + " > ?", c.Value, nil
+	case domain.UserFilterGte:
+		return column + " >= ?", c.Value, nil
+	case domain.UserFilterContains, domain.UserFilterPrefix:
+		s, ok := c.Value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s requires a string value", query.ErrInvalidFilter, c.Operator)
+		}
+		pattern := escapeLike(strings.ToLower(s)) + "%"
+		if c.Operator == domain.UserFilterContains {
+			pattern = "%" + pattern
+		}
+		return "LOWER(" + column + `) LIKE ? ESCAPE '\'`, pattern, nil
+	default:
+		return "", nil, fmt.Errorf("%w: unsupported operator %q", query.ErrInvalidFilter, c.Operator)
+	}
 }
 
-// データベースのエラーを判定するヘルパー関数
-func isDuplicateKeyError(err error) bool {
-	// PostgreSQLの一意性制約違反のエラーコードをチェック
-	// 実際のコードはデータベースに応じて適切に実装する必要があります
-	return err.Error() == "ERROR: duplicate key value violates unique constraint"
+// LIKE のワイルドカードをエスケープ
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
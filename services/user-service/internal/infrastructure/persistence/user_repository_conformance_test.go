@@ -0,0 +1,424 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	runUserRepositoryConformance(t, func(t *testing.T) domain.UserRepository {
+		return NewMemoryUserRepository()
+	}, false)
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	runUserRepositoryConformance(t, func(t *testing.T) domain.UserRepository {
+		db, err := database.NewSQLiteDB(":memory:")
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		t.Cleanup(func() {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		})
+
+		repo, err := NewSQLiteUserRepository(db)
+		if err != nil {
+			t.Fatalf("failed to create repository: %v", err)
+		}
+		return repo
+	}, false)
+}
+
+func TestEncryptedSQLiteUserRepository(t *testing.T) {
+	runUserRepositoryConformance(t, func(t *testing.T) domain.UserRepository {
+		return NewEncryptedUserRepository(newSQLiteTestDB(t), newTestEncryptor(t, "k1", "k1"))
+	}, true)
+}
+
+// USER_SERVICE_TEST_POSTGRES_DSN が設定されている場合のみ実行する
+func TestPostgresUserRepository(t *testing.T) {
+	dsn := os.Getenv("USER_SERVICE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("USER_SERVICE_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := database.NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	runUserRepositoryConformance(t, func(t *testing.T) domain.UserRepository {
+		if err := db.Exec("TRUNCATE user_models CASCADE").Error; err != nil {
+			t.Fatalf("failed to truncate: %v", err)
+		}
+		return NewUserRepository(db)
+	}, false)
+}
+
+// すべての domain.UserRepository 実装が満たすべき振る舞い
+// encrypted の場合、暗号化した列（email, name）の並べ替えと部分一致は拒否されること
+func runUserRepositoryConformance(t *testing.T, newRepo func(t *testing.T) domain.UserRepository, encrypted bool) {
+	ctx := context.Background()
+
+	newUser := func(email string) *domain.User {
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		return &domain.User{
+			Email:     email,
+			Password:  "hashed-password",
+			Name:      "Taro Yamada",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+
+	t.Run("Create assigns ID and initial version", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if user.ID == "" {
+			t.Error("Create() did not assign an ID")
+		}
+		if user.Version != 1 {
+			t.Errorf("Version = %d, want 1", user.Version)
+		}
+	})
+
+	t.Run("Find returns stored user", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+
+		byID, err := repo.FindByID(ctx, user.ID)
+		if err != nil || byID == nil {
+			t.Fatalf("FindByID() = %v, %v", byID, err)
+		}
+		byEmail, err := repo.FindByEmail(ctx, user.Email)
+		if err != nil || byEmail == nil {
+			t.Fatalf("FindByEmail() = %v, %v", byEmail, err)
+		}
+
+		for _, got := range []*domain.User{byID, byEmail} {
+			if got.ID != user.ID || got.Email != user.Email || got.Name != user.Name || got.Password != user.Password {
+				t.Errorf("found user = %+v, want %+v", got, user)
+			}
+			if got.Version != 1 {
+				t.Errorf("Version = %d, want 1", got.Version)
+			}
+			if !got.CreatedAt.Equal(user.CreatedAt) {
+				t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, user.CreatedAt)
+			}
+		}
+	})
+
+	t.Run("Find returns nil for missing user", func(t *testing.T) {
+		repo := newRepo(t)
+
+		byID, err := repo.FindByID(ctx, "00000000-0000-0000-0000-000000000000")
+		if err != nil || byID != nil {
+			t.Errorf("FindByID() = %v, %v, want nil, nil", byID, err)
+		}
+		byEmail, err := repo.FindByEmail(ctx, "missing@example.com")
+		if err != nil || byEmail != nil {
+			t.Errorf("FindByEmail() = %v, %v, want nil, nil", byEmail, err)
+		}
+	})
+
+	t.Run("FindByIDs skips missing and deleted users", func(t *testing.T) {
+		repo := newRepo(t)
+		taro, hanako := newUser("taro@example.com"), newUser("hanako@example.com")
+		for _, user := range []*domain.User{taro, hanako} {
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Delete(ctx, hanako.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		users, err := repo.FindByIDs(ctx, []string{taro.ID, hanako.ID, "00000000-0000-0000-0000-000000000000", taro.ID})
+		if err != nil {
+			t.Fatalf("FindByIDs() error = %v", err)
+		}
+		if len(users) != 1 || users[0].ID != taro.ID || users[0].Email != taro.Email {
+			t.Errorf("FindByIDs() = %+v, want only %s", users, taro.ID)
+		}
+
+		if users, err := repo.FindByIDs(ctx, nil); err != nil || len(users) != 0 {
+			t.Errorf("FindByIDs(nil) = %v, %v", users, err)
+		}
+	})
+
+	t.Run("Create rejects duplicate email", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.Create(ctx, newUser("taro@example.com")); err != nil {
+			t.Fatal(err)
+		}
+
+		err := repo.Create(ctx, newUser("taro@example.com"))
+		if !errors.Is(err, domain.ErrEmailAlreadyExists) {
+			t.Errorf("Create() error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+		}
+	})
+
+	t.Run("Update increments version", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+
+		user.Name = "Hanako Yamada"
+		user.UpdatedAt = user.UpdatedAt.Add(time.Minute)
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if user.Version != 2 {
+			t.Errorf("Version = %d, want 2", user.Version)
+		}
+
+		got, err := repo.FindByID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "Hanako Yamada" || got.Version != 2 {
+			t.Errorf("stored user = %+v", got)
+		}
+	})
+
+	t.Run("Update with stale version fails", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		stale := *user
+
+		user.Name = "First"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+
+		stale.Name = "Second"
+		err := repo.Update(ctx, &stale)
+		if !errors.Is(err, domain.ErrConcurrentModification) {
+			t.Errorf("Update() error = %v, want %v", err, domain.ErrConcurrentModification)
+		}
+	})
+
+	t.Run("Update of missing user fails", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+		user.ID = "00000000-0000-0000-0000-000000000000"
+		user.Version = 1
+
+		err := repo.Update(ctx, user)
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Update() error = %v, want %v", err, domain.ErrUserNotFound)
+		}
+	})
+
+	t.Run("Delete hides user but keeps email reserved", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("taro@example.com")
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		if got, err := repo.FindByID(ctx, user.ID); err != nil || got != nil {
+			t.Errorf("FindByID() after delete = %v, %v, want nil, nil", got, err)
+		}
+		if got, err := repo.FindByEmail(ctx, user.Email); err != nil || got != nil {
+			t.Errorf("FindByEmail() after delete = %v, %v, want nil, nil", got, err)
+		}
+		if err := repo.Update(ctx, user); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Update() after delete error = %v, want %v", err, domain.ErrUserNotFound)
+		}
+		if err := repo.Create(ctx, newUser(user.Email)); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+			t.Errorf("Create() with deleted user's email error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+		}
+	})
+
+	t.Run("Delete of missing user succeeds", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if err := repo.Delete(ctx, "00000000-0000-0000-0000-000000000000"); err != nil {
+			t.Errorf("Delete() error = %v", err)
+		}
+	})
+
+	// 作成日時の順に a〜e のユーザーを作成する（d は削除済み）
+	seedList := func(t *testing.T, repo domain.UserRepository) {
+		t.Helper()
+		base := time.Now().UTC().Truncate(time.Millisecond).Add(-time.Hour)
+		for i, name := range []string{"Alice", "bob", "Carol", "Dave", "Eve"} {
+			user := newUser(strings.ToLower(name) + "@example.com")
+			user.Name = name
+			user.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			if err := repo.Create(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+			if name == "Dave" {
+				if err := repo.Delete(ctx, user.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	// params で1ページ取得する
+	listPage := func(t *testing.T, repo domain.UserRepository, params query.Params) query.Page[*domain.User] {
+		t.Helper()
+		users, err := repo.List(ctx, toUserListParams(params))
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		return query.NewPage(users, params, func(u *domain.User) (string, string) {
+			return u.SortValue(params.Sort.Field), u.ID
+		})
+	}
+
+	names := func(users []*domain.User) string {
+		var s []string
+		for _, u := range users {
+			s = append(s, u.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	t.Run("List pages forward and backward", func(t *testing.T) {
+		repo := newRepo(t)
+		seedList(t, repo)
+		params := query.Params{Limit: 2, Sort: query.Sort{Field: domain.UserFieldCreatedAt}}
+
+		first := listPage(t, repo, params)
+		if got := names(first.Items); got != "Alice,bob" || first.Next == nil || first.Prev != nil {
+			t.Fatalf("first page = %s (next %v, prev %v)", got, first.Next, first.Prev)
+		}
+
+		params.Cursor = first.Next
+		second := listPage(t, repo, params)
+		if got := names(second.Items); got != "Carol,Eve" || second.Next != nil || second.Prev == nil {
+			t.Fatalf("second page = %s (next %v, prev %v)", got, second.Next, second.Prev)
+		}
+
+		params.Cursor = second.Prev
+		back := listPage(t, repo, params)
+		if got := names(back.Items); got != "Alice,bob" || back.Next == nil || back.Prev != nil {
+			t.Errorf("previous page = %s (next %v, prev %v)", got, back.Next, back.Prev)
+		}
+	})
+
+	t.Run("List sorts descending by name", func(t *testing.T) {
+		repo := newRepo(t)
+		seedList(t, repo)
+		params := query.Params{Limit: 10, Sort: query.Sort{Field: domain.UserFieldName, Desc: true}}
+
+		if encrypted {
+			if _, err := repo.List(ctx, toUserListParams(params)); !errors.Is(err, query.ErrInvalidSort) {
+				t.Errorf("List() error = %v, want %v", err, query.ErrInvalidSort)
+			}
+			return
+		}
+
+		page := listPage(t, repo, params)
+		if got := names(page.Items); got != "bob,Eve,Carol,Alice" {
+			t.Errorf("List() = %s", got)
+		}
+	})
+
+	t.Run("List applies filters", func(t *testing.T) {
+		repo := newRepo(t)
+		seedList(t, repo)
+
+		all := listPage(t, repo, query.Params{Limit: 10, Sort: query.Sort{Field: domain.UserFieldCreatedAt}})
+		carolCreatedAt := all.Items[2].CreatedAt
+
+		tests := []struct {
+			name    string
+			filters []query.Condition
+			want    string
+			// 暗号化した列の部分一致
+			needsPlaintext bool
+		}{
+			{name: "contains is case-insensitive", filters: []query.Condition{{Field: domain.UserFieldName, Operator: query.OpContains, Value: "O"}}, want: "bob,Carol", needsPlaintext: true},
+			{name: "prefix", filters: []query.Condition{{Field: domain.UserFieldEmail, Operator: query.OpPrefix, Value: "e"}}, want: "Eve", needsPlaintext: true},
+			{name: "wildcards are literal", filters: []query.Condition{{Field: domain.UserFieldName, Operator: query.OpContains, Value: "%"}}, want: "", needsPlaintext: true},
+			{name: "equal", filters: []query.Condition{{Field: domain.UserFieldEmail, Operator: query.OpEq, Value: "bob@example.com"}}, want: "bob"},
+			{name: "time range", filters: []query.Condition{{Field: domain.UserFieldCreatedAt, Operator: query.OpGte, Value: carolCreatedAt}}, want: "Carol,Eve"},
+			{
+				name: "combined",
+				filters: []query.Condition{
+					{Field: domain.UserFieldCreatedAt, Operator: query.OpLt, Value: carolCreatedAt},
+					{Field: domain.UserFieldName, Operator: query.OpContains, Value: "b"},
+				},
+				want:           "bob",
+				needsPlaintext: true,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if encrypted && tt.needsPlaintext {
+					params := query.Params{Limit: 10, Sort: query.Sort{Field: domain.UserFieldCreatedAt}, Filters: tt.filters}
+					if _, err := repo.List(ctx, toUserListParams(params)); !errors.Is(err, query.ErrInvalidFilter) {
+						t.Errorf("List() error = %v, want %v", err, query.ErrInvalidFilter)
+					}
+					return
+				}
+
+				page := listPage(t, repo, query.Params{Limit: 10, Sort: query.Sort{Field: domain.UserFieldCreatedAt}, Filters: tt.filters})
+				if got := names(page.Items); got != tt.want {
+					t.Errorf("List() = %s, want %s", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+// 一覧取得の条件をリポジトリの取得条件に変換する（1件多く取得する）
+func toUserListParams(params query.Params) domain.UserListParams {
+	listParams := domain.UserListParams{
+		Limit:     params.Limit + 1,
+		SortField: params.Sort.Field,
+		Desc:      params.Sort.Desc != (params.Direction() == query.DirectionPrev),
+	}
+	for _, c := range params.Filters {
+		listParams.Filters = append(listParams.Filters, domain.UserFilter{Field: c.Field, Operator: domain.UserFilterOperator(c.Operator), Value: c.Value})
+	}
+	if params.Cursor != nil {
+		listParams.After = &domain.UserListPosition{Value: params.Cursor.Value, ID: params.Cursor.ID}
+	}
+	return listParams
+}
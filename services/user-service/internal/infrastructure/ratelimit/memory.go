@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// メモリ上の保存先（単一プロセス・テスト用）
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	now     func() time.Time
+	// 次に古いバケットを掃除する時刻
+	nextSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// 上限まで回復し、削除してよい時刻
+	expiresAt time.Time
+}
+
+// 掃除の間隔
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]memoryBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, result := take(s.buckets[key].bucket, now, limit)
+	s.buckets[key] = memoryBucket{bucket: b, expiresAt: now.Add(result.ResetAfter)}
+	return result, nil
+}
+
+// 上限まで回復したバケットの削除（状態を持たない場合と同じ結果になるため）
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, b := range s.buckets {
+		if !now.Before(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.nextSweep = now.Add(sweepInterval)
+}
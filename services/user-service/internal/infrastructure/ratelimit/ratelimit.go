@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// 制限（Window の間に Requests 回まで。バーストも Requests 回まで許可する）
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// トークンが1つ補充されるまでの時間
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// 判定結果
+type Result struct {
+	Allowed bool
+	Limit   int
+	// 残りのリクエスト数
+	Remaining int
+	// 上限まで回復するまでの時間
+	ResetAfter time.Duration
+	// 拒否された場合、次のリクエストが許可されるまでの時間
+	RetryAfter time.Duration
+}
+
+// レート制限の状態の保存先
+type Store interface {
+	// key のリクエストを1回消費できるか判定する
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// トークンバケットの状態
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// トークンバケットの判定（メモリとRedisで同じ計算をする）
+//
+// バケットは Requests 個のトークンを持ち、Window / Requests ごとに1つ補充される。
+// リクエストごとに1つ消費し、空の場合は拒否する。
+func take(b bucket, now time.Time, limit Limit) (bucket, Result) {
+	capacity := float64(limit.Requests)
+	interval := limit.interval()
+
+	// 1. 経過時間に応じて補充する
+	tokens := capacity
+	if !b.last.IsZero() {
+		elapsed := now.Sub(b.last)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(interval))
+	}
+
+	// 2. 消費する
+	result := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(interval))
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = time.Duration((capacity - tokens) * float64(interval))
+
+	return bucket{tokens: tokens, last: now}, result
+}
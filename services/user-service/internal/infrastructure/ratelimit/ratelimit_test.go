@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 時刻を進められる保存先（テスト用）
+type testStore struct {
+	Store
+	advance func(d time.Duration)
+}
+
+func newTestMemoryStore(t *testing.T) testStore {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	return testStore{Store: store, advance: func(d time.Duration) { now = now.Add(d) }}
+}
+
+func newTestRedisStore(t *testing.T) testStore {
+	server := miniredis.RunT(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return testStore{Store: NewRedisStore(client), advance: func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+	}}
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) testStore{
+		"memory": newTestMemoryStore,
+		"redis":  newTestRedisStore,
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("limit is enforced and refilled", func(t *testing.T) {
+				store := newStore(t)
+				ctx := context.Background()
+				limit := Limit{Requests: 3, Window: 3 * time.Second}
+
+				// 上限まで許可する
+				for i := 0; i < 3; i++ {
+					result, err := store.Allow(ctx, "client", limit)
+					if err != nil {
+						t.Fatalf("Allow() error = %v", err)
+					}
+					if !result.Allowed || result.Remaining != 2-i {
+						t.Fatalf("request %d: got %+v", i, result)
+					}
+				}
+
+				// 超過したら拒否し、次に許可されるまでの時間を返す
+				result, err := store.Allow(ctx, "client", limit)
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+				if result.Allowed || result.Remaining != 0 {
+					t.Fatalf("expected rejection, got %+v", result)
+				}
+				if result.RetryAfter <= 0 || result.RetryAfter > time.Second {
+					t.Errorf("RetryAfter = %v, want (0, 1s]", result.RetryAfter)
+				}
+				if result.ResetAfter != 3*time.Second {
+					t.Errorf("ResetAfter = %v, want 3s", result.ResetAfter)
+				}
+
+				// トークンが1つ補充されると再び許可する
+				store.advance(time.Second)
+				result, err = store.Allow(ctx, "client", limit)
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+				if !result.Allowed {
+					t.Fatalf("expected request to be allowed after refill, got %+v", result)
+				}
+			})
+
+			t.Run("keys are isolated", func(t *testing.T) {
+				store := newStore(t)
+				ctx := context.Background()
+				limit := Limit{Requests: 1, Window: time.Minute}
+
+				if result, _ := store.Allow(ctx, "a", limit); !result.Allowed {
+					t.Fatalf("first request for a should be allowed")
+				}
+				if result, _ := store.Allow(ctx, "a", limit); result.Allowed {
+					t.Fatalf("second request for a should be rejected")
+				}
+				if result, _ := store.Allow(ctx, "b", limit); !result.Allowed {
+					t.Fatalf("first request for b should be allowed")
+				}
+			})
+		})
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 10, Window: time.Second}
+
+	store.Allow(context.Background(), "old", limit)
+	now = now.Add(2 * sweepInterval)
+	store.Allow(context.Background(), "new", limit)
+
+	if _, ok := store.buckets["old"]; ok {
+		t.Error("expected recovered bucket to be swept")
+	}
+	if _, ok := store.buckets["new"]; !ok {
+		t.Error("expected active bucket to be kept")
+	}
+}
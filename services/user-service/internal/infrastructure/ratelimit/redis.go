@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redisのキーの接頭辞
+const redisKeyPrefix = "ratelimit:"
+
+// トークンバケットの判定を原子的に行うスクリプト（take と同じ計算）
+// 時刻はRedisサーバーの時計を使い、レプリカ間の時計のずれの影響を受けないようにする
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+
+local now = redis.call("TIME")
+local now_us = tonumber(now[1]) * 1000000 + tonumber(now[2])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = capacity
+if state[1] then
+  local elapsed = math.max(0, now_us - tonumber(state[2]))
+  tokens = math.min(capacity, tonumber(state[1]) + elapsed / interval)
+end
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry_after = (1 - tokens) * interval
+end
+local reset_after = (capacity - tokens) * interval
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now_us)
+redis.call("PEXPIRE", KEYS[1], math.ceil(reset_after / 1000) + 1000)
+
+return {allowed, math.floor(tokens), math.ceil(reset_after), math.ceil(retry_after)}
+`)
+
+// Redisの保存先（複数のレプリカで制限を共有する）
+type RedisStore struct {
+	client redis.Scripter
+}
+
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	interval := limit.interval().Microseconds()
+	values, err := takeScript.Run(ctx, s.client, []string{redisKeyPrefix + key}, limit.Requests, interval).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script failed: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("rate limit script returned %d values", len(values))
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Requests,
+		Remaining:  int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
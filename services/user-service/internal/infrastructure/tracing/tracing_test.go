@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// 作成されたスパンを記録する TracerProvider をグローバルに設定する
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func attributeValue(attrs []attribute.KeyValue, key string) string {
+	for _, attr := range attrs {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestSetup(t *testing.T) {
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	t.Run("file exporter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+		shutdown, err := Setup(context.Background(), Config{
+			ServiceName: "user-service",
+			Environment: "test",
+			Exporter:    ExporterFile,
+			FilePath:    path,
+			SampleRatio: 1,
+		})
+		if err != nil {
+			t.Fatalf("Setup() error = %v", err)
+		}
+
+		_, span := Tracer().Start(context.Background(), "test-span")
+		span.End()
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown() error = %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read traces: %v", err)
+		}
+		if !strings.Contains(string(data), `"Name":"test-span"`) || !strings.Contains(string(data), "user-service") {
+			t.Errorf("trace file = %s", data)
+		}
+	})
+
+	t.Run("none still propagates", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+		if err != nil {
+			t.Fatalf("Setup() error = %v", err)
+		}
+		defer shutdown(context.Background())
+
+		fields := otel.GetTextMapPropagator().Fields()
+		if strings.Join(fields, ",") != "traceparent,tracestate,baggage" {
+			t.Errorf("propagator fields = %v", fields)
+		}
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+			t.Error("Setup() with an unknown exporter should fail")
+		}
+	})
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := useRecorder(t)
+
+	db, err := database.NewSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	type record struct {
+		ID    uint
+		Email string
+	}
+	if err := db.AutoMigrate(&record{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	db.WithContext(ctx).Create(&record{Email: "taro@example.com"})
+	var found record
+	db.WithContext(ctx).Where("email = ?", "taro@example.com").First(&found)
+	db.WithContext(ctx).Where("email = ?", "missing@example.com").First(&found)
+	db.WithContext(ctx).Exec("SELECT * FROM missing_table")
+	parent.End()
+
+	// AutoMigrate のクエリを除き、リクエストのスパンの子のみ集計する
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			spans[span.Name()] = append(spans[span.Name()], span)
+		}
+	}
+
+	if len(spans["gorm.create"]) != 1 || len(spans["gorm.query"]) != 2 || len(spans["gorm.raw"]) != 1 {
+		t.Fatalf("spans = %v", spans)
+	}
+	for _, span := range append(spans["gorm.create"], spans["gorm.query"]...) {
+		statement := attributeValue(span.Attributes(), "db.statement")
+		if statement == "" || strings.Contains(statement, "example.com") {
+			t.Errorf("db.statement = %q", statement)
+		}
+		if attributeValue(span.Attributes(), "db.system") != "sqlite" {
+			t.Errorf("db.system = %q", attributeValue(span.Attributes(), "db.system"))
+		}
+	}
+	// 見つからないことはエラーにしない
+	for _, span := range spans["gorm.query"] {
+		if span.Status().Code == codes.Error {
+			t.Errorf("query span status = %v", span.Status())
+		}
+	}
+	if spans["gorm.raw"][0].Status().Code != codes.Error {
+		t.Errorf("failed query span status = %v", spans["gorm.raw"][0].Status())
+	}
+}
+
+func TestNewHTTPClient_PropagatesTraceContext(t *testing.T) {
+	recorder := useRecorder(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := NewHTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("traceparent = %q, want trace ID %s", traceparent, traceID)
+	}
+	if len(recorder.Ended()) != 2 {
+		t.Errorf("recorded %d spans, want the client span and its parent", len(recorder.Ended()))
+	}
+}
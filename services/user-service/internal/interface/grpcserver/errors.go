@@ -0,0 +1,48 @@
+// services/user-service/internal/interface/grpcserver/errors.go
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/interceptor"
+	"google.golang.org/grpc/codes"
+)
+
+// HTTPのステータスと gRPC のコードの対応
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.Aborted,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+}
+
+// ドメインエラーを gRPC のステータスに変換する
+// REST API と同じエラーコードを ErrorInfo の reason に設定する
+func toStatus(ctx context.Context, err error) error {
+	p := apierror.FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "grpc request failed", "error", err)
+	}
+
+	code, ok := grpcCodes[p.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	// 一意制約の違反は再試行しても成功しないため AlreadyExists とする
+	if p.Code == apierror.CodeEmailAlreadyExists {
+		code = codes.AlreadyExists
+	}
+	return interceptor.StatusError(code, p.Code, p.Detail)
+}
+
+// リクエストの形式の誤り
+func invalidArgument(msg string) error {
+	return interceptor.StatusError(codes.InvalidArgument, apierror.CodeInvalidRequest, msg)
+}
@@ -0,0 +1,53 @@
+// services/user-service/internal/interface/grpcserver/server.go
+package grpcserver
+
+import (
+	"context"
+
+	userv1 "github.com/MizukiMachine/ecommerce-microservices/services/user-service/api/proto/user/v1"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/interceptor"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// gRPC サーバーへのサービスの登録
+func Register(server grpc.ServiceRegistrar, users *UserServer) {
+	userv1.RegisterUserServiceServer(server, users)
+}
+
+// メソッドごとの認可の設定（interceptor.AuthInterceptor 用）
+func MethodPolicies() map[string]interceptor.MethodPolicy {
+	return map[string]interceptor.MethodPolicy{
+		userv1.UserService_GetUser_FullMethodName:       {Scopes: []string{auth.ScopeUsersRead}},
+		userv1.UserService_BatchGetUsers_FullMethodName: {Scopes: []string{auth.ScopeUsersRead}},
+		// 検証するトークンは要求の本文で受け取るため、呼び出し元の認証は不要
+		userv1.UserService_ValidateToken_FullMethodName:   {Public: true},
+		userv1.UserService_CheckPermission_FullMethodName: {Public: true},
+		// ヘルスチェックとサーバーリフレクション
+		"/" + healthpb.Health_ServiceDesc.ServiceName + "/": {Public: true},
+		"/grpc.reflection.v1.ServerReflection/":             {Public: true},
+		"/grpc.reflection.v1alpha.ServerReflection/":        {Public: true},
+	}
+}
+
+// gRPC サーバーの停止（lifecycle.Manager.AddServer 用）
+// 処理中の呼び出しの完了を待ち、猶予時間を過ぎた場合は接続を切断する
+func Shutdown(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			<-stopped
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// services/user-service/internal/interface/grpcserver/user_server.go
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+
+	userv1 "github.com/MizukiMachine/ecommerce-microservices/services/user-service/api/proto/user/v1"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/usecase"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// BatchGetUsers で一度に取得できる最大件数
+const maxBatchSize = 100
+
+// gRPC のユーザーサービスの実装
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	userUseCase *usecase.UserUseCase
+	jwtService  *auth.JWTService
+}
+
+// サーバーの作成
+func NewUserServer(userUseCase *usecase.UserUseCase, jwtService *auth.JWTService) *UserServer {
+	return &UserServer{
+		userUseCase: userUseCase,
+		jwtService:  jwtService,
+	}
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userUseCase.GetUserByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &userv1.GetUserResponse{User: toUser(user)}, nil
+}
+
+func (s *UserServer) BatchGetUsers(ctx context.Context, req *userv1.BatchGetUsersRequest) (*userv1.BatchGetUsersResponse, error) {
+	// 1. 入力の検証
+	ids := req.GetIds()
+	if len(ids) > maxBatchSize {
+		return nil, invalidArgument(fmt.Sprintf("at most %d ids can be requested at once", maxBatchSize))
+	}
+	for _, id := range ids {
+		if err := validateID(id); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. ユーザーの取得
+	users, err := s.userUseCase.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	// 3. 見つからなかった ID を要求した順に返す
+	res := &userv1.BatchGetUsersResponse{Users: make([]*userv1.User, 0, len(users))}
+	found := make(map[string]bool, len(ids))
+	for _, user := range users {
+		res.Users = append(res.Users, toUser(user))
+		found[user.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			res.NotFoundIds = append(res.NotFoundIds, id)
+			found[id] = true
+		}
+	}
+	return res, nil
+}
+
+func (s *UserServer) ValidateToken(ctx context.Context, req *userv1.ValidateTokenRequest) (*userv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, invalidArgument("token is required")
+	}
+
+	// 無効なトークンはエラーではなく valid=false として返す
+	claims, err := s.jwtService.ValidateToken(req.GetToken())
+	if err != nil {
+		return &userv1.ValidateTokenResponse{Valid: false}, nil
+	}
+	return &userv1.ValidateTokenResponse{Valid: true, Claims: toTokenClaims(claims)}, nil
+}
+
+func (s *UserServer) CheckPermission(ctx context.Context, req *userv1.CheckPermissionRequest) (*userv1.CheckPermissionResponse, error) {
+	// 1. 入力の検証
+	if req.GetToken() == "" {
+		return nil, invalidArgument("token is required")
+	}
+	if req.MaxAuthAge != nil {
+		if err := req.MaxAuthAge.CheckValid(); err != nil || req.MaxAuthAge.AsDuration() < 0 {
+			return nil, invalidArgument("max_auth_age must be a non-negative duration")
+		}
+	}
+
+	// 2. トークンの検証
+	claims, err := s.jwtService.ValidateToken(req.GetToken())
+	if err != nil {
+		return &userv1.CheckPermissionResponse{Reason: userv1.CheckPermissionResponse_REASON_INVALID_TOKEN}, nil
+	}
+
+	// 3. スコープの確認
+	var missing []string
+	for _, scope := range req.GetScopes() {
+		if !claims.HasScopes(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return &userv1.CheckPermissionResponse{
+			Reason:        userv1.CheckPermissionResponse_REASON_INSUFFICIENT_SCOPE,
+			MissingScopes: missing,
+		}, nil
+	}
+
+	// 4. 認証の新しさの確認（RequireRecentAuth と同じ基準）
+	if req.MaxAuthAge != nil && !claims.AuthenticatedWithin(req.MaxAuthAge.AsDuration()) {
+		return &userv1.CheckPermissionResponse{Reason: userv1.CheckPermissionResponse_REASON_REAUTHENTICATION_REQUIRED}, nil
+	}
+
+	return &userv1.CheckPermissionResponse{Allowed: true}, nil
+}
+
+// ユーザーIDの形式の検証（データベースに問い合わせる前に不正な値を拒否する）
+func validateID(id string) error {
+	if id == "" {
+		return invalidArgument("id is required")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return invalidArgument(fmt.Sprintf("invalid id: %q", id))
+	}
+	return nil
+}
+
+func toUser(user *usecase.UserOutput) *userv1.User {
+	return &userv1.User{
+		Id:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   int64(user.Version),
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+}
+
+func toTokenClaims(claims *auth.JWTClaims) *userv1.TokenClaims {
+	res := &userv1.TokenClaims{
+		UserId:   claims.UserID,
+		Email:    claims.Email,
+		Scopes:   claims.Scopes(),
+		Issuer:   claims.Issuer,
+		Audience: claims.Audience,
+		Acr:      claims.ACR,
+		Locale:   claims.Locale,
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = timestamppb.New(claims.IssuedAt.Time)
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	if claims.AuthTime != nil {
+		res.AuthTime = timestamppb.New(claims.AuthTime.Time)
+	}
+	return res
+}
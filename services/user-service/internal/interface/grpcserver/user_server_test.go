@@ -0,0 +1,354 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	userv1 "github.com/MizukiMachine/ecommerce-microservices/services/user-service/api/proto/user/v1"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/geo"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/interceptor"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/notification"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/usecase"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const testPassword = "Password123"
+
+// SQLiteを使ってサービス全体を組み立て、メモリ上の接続で呼び出すテスト用サーバー
+type testServer struct {
+	client      userv1.UserServiceClient
+	userUseCase *usecase.UserUseCase
+	jwtService  *auth.JWTService
+	// users:read スコープを持つサービス間通信用のトークン
+	serviceToken string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db, err := database.NewSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	if err := persistence.AutoMigrateModels(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	jwtService := auth.NewJWTService(auth.Config{
+		SecretKey:     "test-secret",
+		Expires:       time.Hour,
+		DefaultScopes: []string{auth.ScopeProfileRead, auth.ScopeProfileWrite},
+	})
+	loginMonitor := usecase.NewLoginMonitor(
+		persistence.NewLoginEventRepository(db),
+		persistence.NewLoginChallengeRepository(db),
+		geo.NewNoopLocator(),
+		notification.NewLogMailer(),
+		usecase.LoginMonitorConfig{},
+	)
+	userUseCase := usecase.NewUserUseCase(
+		persistence.NewUserRepository(db),
+		persistence.NewOutboxRepository(db),
+		persistence.NewTxManager(db),
+		jwtService,
+		loginMonitor,
+	)
+
+	listener := bufconn.Listen(1 << 20)
+	// main.go と同じく認証と認可を行う
+	authInterceptor := interceptor.NewAuthInterceptor(jwtService, MethodPolicies())
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptor.UnaryRecovery(), authInterceptor.Unary()))
+	Register(server, NewUserServer(userUseCase, jwtService))
+	go server.Serve(listener)
+	t.Cleanup(func() { Shutdown(server)(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	serviceToken, err := jwtService.GenerateToken("order-service", "order-service@example.com", auth.ScopeUsersRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		client:       userv1.NewUserServiceClient(conn),
+		userUseCase:  userUseCase,
+		jwtService:   jwtService,
+		serviceToken: serviceToken,
+	}
+}
+
+// トークンをメタデータに付けたコンテキスト
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), interceptor.MetadataAuthorization, "Bearer "+token)
+}
+
+func (s *testServer) createUser(t *testing.T, email string) *usecase.UserOutput {
+	t.Helper()
+	user, err := s.userUseCase.CreateUser(context.Background(), usecase.CreateUserInput{
+		Email:    email,
+		Password: testPassword,
+		Name:     "Taro Yamada",
+		Locale:   "ja",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	return user
+}
+
+// ステータスのコードと ErrorInfo の reason を検証する
+func assertStatus(t *testing.T, err error, wantCode codes.Code, wantReason string) {
+	t.Helper()
+	st, _ := status.FromError(err)
+	if st.Code() != wantCode {
+		t.Fatalf("code = %s, want %s (err = %v)", st.Code(), wantCode, err)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.Reason != wantReason || info.Domain != interceptor.ErrorDomain {
+				t.Errorf("ErrorInfo = %s/%s, want %s/%s", info.Domain, info.Reason, interceptor.ErrorDomain, wantReason)
+			}
+			return
+		}
+	}
+	t.Errorf("status has no ErrorInfo: %v", st.Details())
+}
+
+func TestUserServer_GetUser(t *testing.T) {
+	s := newTestServer(t)
+	ctx := withToken(s.serviceToken)
+	user := s.createUser(t, "taro@example.com")
+
+	res, err := s.client.GetUser(ctx, &userv1.GetUserRequest{Id: user.ID})
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	got := res.GetUser()
+	if got.GetId() != user.ID || got.GetEmail() != "taro@example.com" || got.GetLocale() != "ja" || got.GetVersion() != 1 {
+		t.Errorf("user = %v", got)
+	}
+	if !got.GetCreatedAt().AsTime().Equal(user.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", got.GetCreatedAt().AsTime(), user.CreatedAt)
+	}
+
+	// 存在しないユーザーは REST API と同じエラーコードで返す
+	_, err = s.client.GetUser(ctx, &userv1.GetUserRequest{Id: "00000000-0000-0000-0000-000000000000"})
+	assertStatus(t, err, codes.NotFound, apierror.CodeUserNotFound)
+
+	for _, id := range []string{"", "not-a-uuid"} {
+		_, err = s.client.GetUser(ctx, &userv1.GetUserRequest{Id: id})
+		assertStatus(t, err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+	}
+}
+
+func TestUserServer_BatchGetUsers(t *testing.T) {
+	s := newTestServer(t)
+	ctx := withToken(s.serviceToken)
+	taro := s.createUser(t, "taro@example.com")
+	hanako := s.createUser(t, "hanako@example.com")
+	missing := "00000000-0000-0000-0000-000000000000"
+
+	res, err := s.client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{Ids: []string{hanako.ID, missing, taro.ID, missing, hanako.ID}})
+	if err != nil {
+		t.Fatalf("BatchGetUsers() error = %v", err)
+	}
+	var emails []string
+	for _, u := range res.GetUsers() {
+		emails = append(emails, u.GetEmail())
+	}
+	if got := strings.Join(emails, ","); got != "hanako@example.com,taro@example.com" {
+		t.Errorf("users = %s", got)
+	}
+	if got := strings.Join(res.GetNotFoundIds(), ","); got != missing {
+		t.Errorf("not_found_ids = %s", got)
+	}
+
+	// 空の要求は空の応答を返す
+	res, err = s.client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{})
+	if err != nil || len(res.GetUsers()) != 0 || len(res.GetNotFoundIds()) != 0 {
+		t.Errorf("BatchGetUsers(empty) = %v, %v", res, err)
+	}
+
+	tooMany := make([]string, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = missing
+	}
+	_, err = s.client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{Ids: tooMany})
+	assertStatus(t, err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+
+	_, err = s.client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{Ids: []string{taro.ID, "bad"}})
+	assertStatus(t, err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+}
+
+func TestUserServer_Authorization(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser(t, "taro@example.com")
+	req := &userv1.GetUserRequest{Id: user.ID}
+
+	// ユーザーの取得には users:read スコープが必要
+	_, err := s.client.GetUser(context.Background(), req)
+	assertStatus(t, err, codes.Unauthenticated, apierror.CodeUnauthorized)
+
+	_, err = s.client.GetUser(withToken("invalid"), req)
+	assertStatus(t, err, codes.Unauthenticated, apierror.CodeInvalidToken)
+
+	userToken, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.client.BatchGetUsers(withToken(userToken), &userv1.BatchGetUsersRequest{Ids: []string{user.ID}})
+	assertStatus(t, err, codes.PermissionDenied, apierror.CodeInsufficientScope)
+
+	// トークンの検証は呼び出し元の認証なしで使える
+	if _, err := s.client.ValidateToken(context.Background(), &userv1.ValidateTokenRequest{Token: userToken}); err != nil {
+		t.Errorf("ValidateToken() without credentials error = %v", err)
+	}
+}
+
+func TestUserServer_ValidateToken(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	token, err := s.jwtService.IssueToken(auth.TokenParams{
+		UserID:   "user-1",
+		Email:    "taro@example.com",
+		Scopes:   []string{auth.ScopeProfileRead, auth.ScopeUsersRead},
+		AuthTime: authTime,
+		ACR:      auth.ACRPassword,
+		Locale:   "ja",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.client.ValidateToken(ctx, &userv1.ValidateTokenRequest{Token: token})
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	claims := res.GetClaims()
+	if !res.GetValid() || claims.GetUserId() != "user-1" || claims.GetEmail() != "taro@example.com" || claims.GetAcr() != auth.ACRPassword || claims.GetLocale() != "ja" {
+		t.Errorf("response = %v", res)
+	}
+	if got := strings.Join(claims.GetScopes(), " "); got != "profile:read users:read" {
+		t.Errorf("scopes = %q", got)
+	}
+	if !claims.GetAuthTime().AsTime().Equal(authTime) || claims.GetExpiresAt() == nil {
+		t.Errorf("auth_time, expires_at = %v, %v", claims.GetAuthTime(), claims.GetExpiresAt())
+	}
+
+	// 無効なトークンはエラーにしない
+	res, err = s.client.ValidateToken(ctx, &userv1.ValidateTokenRequest{Token: "invalid"})
+	if err != nil || res.GetValid() || res.GetClaims() != nil {
+		t.Errorf("ValidateToken(invalid) = %v, %v", res, err)
+	}
+
+	_, err = s.client.ValidateToken(ctx, &userv1.ValidateTokenRequest{})
+	assertStatus(t, err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+}
+
+func TestUserServer_CheckPermission(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	issue := func(authAge time.Duration, scopes ...string) string {
+		token, err := s.jwtService.IssueToken(auth.TokenParams{
+			UserID:   "user-1",
+			Email:    "taro@example.com",
+			Scopes:   scopes,
+			AuthTime: time.Now().Add(-authAge),
+			ACR:      auth.ACRPassword,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	recent := issue(time.Minute, auth.ScopeProfileRead, auth.ScopeUsersRead)
+	stale := issue(time.Hour, auth.ScopeProfileRead)
+
+	tests := []struct {
+		name        string
+		req         *userv1.CheckPermissionRequest
+		wantAllowed bool
+		wantReason  userv1.CheckPermissionResponse_Reason
+		wantMissing string
+	}{
+		{
+			name:        "granted",
+			req:         &userv1.CheckPermissionRequest{Token: recent, Scopes: []string{auth.ScopeUsersRead}},
+			wantAllowed: true,
+		},
+		{
+			name:        "no scopes required",
+			req:         &userv1.CheckPermissionRequest{Token: stale},
+			wantAllowed: true,
+		},
+		{
+			name:        "missing scopes",
+			req:         &userv1.CheckPermissionRequest{Token: stale, Scopes: []string{auth.ScopeProfileRead, auth.ScopeUsersRead, auth.ScopeProfileWrite}},
+			wantReason:  userv1.CheckPermissionResponse_REASON_INSUFFICIENT_SCOPE,
+			wantMissing: "users:read,profile:write",
+		},
+		{
+			name:        "recent authentication",
+			req:         &userv1.CheckPermissionRequest{Token: recent, MaxAuthAge: durationpb.New(5 * time.Minute)},
+			wantAllowed: true,
+		},
+		{
+			name:       "reauthentication required",
+			req:        &userv1.CheckPermissionRequest{Token: stale, MaxAuthAge: durationpb.New(5 * time.Minute)},
+			wantReason: userv1.CheckPermissionResponse_REASON_REAUTHENTICATION_REQUIRED,
+		},
+		{
+			name:       "invalid token",
+			req:        &userv1.CheckPermissionRequest{Token: "invalid"},
+			wantReason: userv1.CheckPermissionResponse_REASON_INVALID_TOKEN,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.client.CheckPermission(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("CheckPermission() error = %v", err)
+			}
+			if res.GetAllowed() != tt.wantAllowed || res.GetReason() != tt.wantReason {
+				t.Errorf("allowed, reason = %v, %s, want %v, %s", res.GetAllowed(), res.GetReason(), tt.wantAllowed, tt.wantReason)
+			}
+			if got := strings.Join(res.GetMissingScopes(), ","); got != tt.wantMissing {
+				t.Errorf("missing_scopes = %q, want %q", got, tt.wantMissing)
+			}
+		})
+	}
+
+	_, err := s.client.CheckPermission(ctx, &userv1.CheckPermissionRequest{Token: recent, MaxAuthAge: durationpb.New(-time.Minute)})
+	assertStatus(t, err, codes.InvalidArgument, apierror.CodeInvalidRequest)
+}
@@ -0,0 +1,109 @@
+// services/user-service/internal/interface/handler/docs_handler.go
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// APIドキュメントの画面（/openapi.json を表示する）
+// Swagger UI は外部から読み込むため、Subresource Integrity で内容を固定する
+const docsPageTemplate = `<!DOCTYPE html>
+<html lang="ja">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>User Service API</title>
+  <link rel="stylesheet" href="%[1]s/swagger-ui.css" integrity="%[2]s" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%[1]s/swagger-ui-bundle.js" integrity="%[3]s" crossorigin="anonymous"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// APIドキュメントの画面の設定
+type DocsUIConfig struct {
+	// swagger-ui-dist の配置先（swagger-ui.css と swagger-ui-bundle.js を読み込む）
+	AssetsURL string
+	// swagger-ui.css・swagger-ui-bundle.js の Subresource Integrity（"sha384-..."）
+	CSSIntegrity string
+	JSIntegrity  string
+}
+
+// 画面を公開できるか（内容を固定できない場合は公開しない）
+func (c DocsUIConfig) Enabled() bool {
+	return c.AssetsURL != "" && c.CSSIntegrity != "" && c.JSIntegrity != ""
+}
+
+// APIの仕様とドキュメントのハンドラー
+type DocsHandler struct {
+	specYAML []byte
+	specJSON []byte
+	// 画面を公開しない場合は空
+	page []byte
+}
+
+// ハンドラーの作成（YAMLの仕様をJSONにも変換しておく）
+// ui.Enabled() が false の場合は /docs の画面を公開しない
+func NewDocsHandler(spec []byte, ui DocsUIConfig) (*DocsHandler, error) {
+	specJSON, err := OpenAPIJSON(spec)
+	if err != nil {
+		return nil, err
+	}
+	h := &DocsHandler{specYAML: spec, specJSON: specJSON}
+	if ui.Enabled() {
+		h.page = []byte(fmt.Sprintf(docsPageTemplate,
+			html.EscapeString(strings.TrimSuffix(ui.AssetsURL, "/")),
+			html.EscapeString(ui.CSSIntegrity),
+			html.EscapeString(ui.JSIntegrity),
+		))
+	}
+	return h, nil
+}
+
+// YAMLの仕様をJSONに変換する
+func OpenAPIJSON(spec []byte) ([]byte, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(spec, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert OpenAPI spec to JSON: %w", err)
+	}
+	return data, nil
+}
+
+func (h *DocsHandler) YAML(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", h.specYAML)
+}
+
+func (h *DocsHandler) JSON(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.specJSON)
+}
+
+func (h *DocsHandler) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.page)
+}
+
+// APIドキュメントのルーティングの設定（認証不要）
+func RegisterDocsRoutes(router gin.IRouter, h *DocsHandler) {
+	router.GET("/openapi.yaml", h.YAML)
+	router.GET("/openapi.json", h.JSON)
+	if h.page != nil {
+		router.GET("/docs", h.UI)
+	}
+}
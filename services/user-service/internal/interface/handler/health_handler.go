@@ -0,0 +1,47 @@
+// services/user-service/internal/interface/handler/health_handler.go
+package handler
+
+import (
+	"net/http"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/health"
+	"github.com/gin-gonic/gin"
+)
+
+// ヘルスチェックのハンドラー
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+// ハンドラーの作成
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// 生存確認（プロセスが応答できれば成功。依存先は確認しない）
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{
+		Status: health.StatusUp,
+		Checks: map[string]health.CheckResult{},
+	})
+}
+
+// 準備完了の確認（依存先がすべて応答する場合のみ成功）
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// ヘルスチェックのルーティングの設定（認証不要）
+func RegisterHealthRoutes(router gin.IRouter, healthHandler *HealthHandler) {
+	h := router.Group("/health")
+	{
+		h.GET("/live", healthHandler.Live)
+		h.GET("/ready", healthHandler.Ready)
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/health"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var brokerErr error
+	registry := health.NewRegistry(time.Second)
+	registry.Register("database", health.CheckerFunc(func(context.Context) error { return nil }), 0)
+	registry.Register("broker", health.CheckerFunc(func(context.Context) error { return brokerErr }), 0)
+
+	router := gin.New()
+	RegisterHealthRoutes(router, NewHealthHandler(registry))
+
+	get := func(path string) (int, health.Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := get("/health/live"); code != http.StatusOK || report.Status != health.StatusUp {
+		t.Errorf("live = %d %+v", code, report)
+	}
+
+	code, report := get("/health/ready")
+	if code != http.StatusOK || report.Status != health.StatusUp || len(report.Checks) != 2 {
+		t.Errorf("ready = %d %+v", code, report)
+	}
+
+	// 依存先が落ちている場合は 503 で詳細を返す（生存確認は成功のまま）
+	brokerErr = errors.New("nats connection is CLOSED")
+	code, report = get("/health/ready")
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusDown {
+		t.Errorf("ready = %d %+v", code, report)
+	}
+	if got := report.Checks["broker"]; got.Status != health.StatusDown || got.Error != "nats connection is CLOSED" {
+		t.Errorf("Checks[broker] = %+v", got)
+	}
+	if got := report.Checks["database"]; got.Status != health.StatusUp {
+		t.Errorf("Checks[database] = %+v", got)
+	}
+	if code, _ := get("/health/live"); code != http.StatusOK {
+		t.Errorf("live = %d", code)
+	}
+}
@@ -0,0 +1,481 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/api"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/health"
+	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	specURL       = "openapi.json"
+	oasSchemaURL  = "https://spec.openapis.org/oas/3.1/schema/2022-10-07"
+	oasSchemaFile = "testdata/openapi-3.1.schema.json"
+)
+
+// OpenAPI の仕様とレスポンスの検証に使うスキーマ
+type contract struct {
+	document map[string]interface{}
+	compiler *jsonschema.Compiler
+}
+
+func loadContract(t *testing.T) *contract {
+	t.Helper()
+
+	specJSON, err := OpenAPIJSON(api.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(specJSON, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(specURL, bytes.NewReader(specJSON)); err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	return &contract{document: document, compiler: compiler}
+}
+
+// JSON Pointer の各要素をエスケープして連結する
+func jsonPointer(tokens ...string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(escaper.Replace(token))
+	}
+	return b.String()
+}
+
+// 仕様に記載されたレスポンスと、そのスキーマの JSON Pointer を返す
+func (c *contract) response(t *testing.T, method, path string, status int) (map[string]interface{}, []string) {
+	t.Helper()
+
+	paths, _ := c.document["paths"].(map[string]interface{})
+	item, ok := paths[path].(map[string]interface{})
+	if !ok {
+		t.Fatalf("path %s is not documented", path)
+	}
+	operation, ok := item[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s %s is not documented", method, path)
+	}
+	responses, _ := operation["responses"].(map[string]interface{})
+	response, ok := responses[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s %s: status %d is not documented", method, path, status)
+	}
+	pointer := []string{"paths", path, strings.ToLower(method), "responses", strconv.Itoa(status)}
+
+	// components/responses への参照をたどる
+	if ref, ok := response["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/responses/")
+		components, _ := c.document["components"].(map[string]interface{})
+		shared, _ := components["responses"].(map[string]interface{})
+		response, ok = shared[name].(map[string]interface{})
+		if !ok {
+			t.Fatalf("unresolved response reference %s", ref)
+		}
+		pointer = []string{"components", "responses", name}
+	}
+	return response, pointer
+}
+
+// 実際のレスポンスが仕様と一致することを検証する
+func (c *contract) check(t *testing.T, method, path string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+
+	response, pointer := c.response(t, method, path, rec.Code)
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s %s: status %d must not have a body: %s", method, path, rec.Code, rec.Body)
+		}
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("%s %s: invalid Content-Type %q", method, path, rec.Header().Get("Content-Type"))
+	}
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s %s: media type %s is not documented for status %d", method, path, mediaType, rec.Code)
+	}
+	if _, ok := media["schema"]; !ok || !strings.HasSuffix(mediaType, "json") {
+		return
+	}
+
+	pointer = append(pointer, "content", mediaType, "schema")
+	schema, err := c.compiler.Compile(specURL + "#" + jsonPointer(pointer...))
+	if err != nil {
+		t.Fatalf("failed to compile schema %v: %v", pointer, err)
+	}
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: invalid JSON body %q: %v", method, path, rec.Body, err)
+	}
+	if err := schema.Validate(body); err != nil {
+		t.Errorf("%s %s: status %d does not match the spec: %v\nbody: %s", method, path, rec.Code, err, rec.Body)
+	}
+}
+
+// 仕様に記載された操作（"GET /path" の形式）
+func (c *contract) operations() []string {
+	var ops []string
+	paths, _ := c.document["paths"].(map[string]interface{})
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			switch method {
+			case "get", "put", "post", "delete", "patch":
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// テスト用の Swagger UI の設定（ハッシュは実在のファイルのものではない）
+var testDocsUI = DocsUIConfig{
+	AssetsURL:    "https://cdn.example.com/swagger-ui-dist@5.17.14/",
+	CSSIntegrity: "sha384-css",
+	JSIntegrity:  "sha384-js",
+}
+
+// main.go と同じくヘルスチェックと API ドキュメントのルートを追加したテスト用サーバー
+func newContractServer(t *testing.T) *testServer {
+	t.Helper()
+	s := newTestServer(t)
+
+	docsHandler, err := NewDocsHandler(api.OpenAPI, testDocsUI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterHealthRoutes(s.router, NewHealthHandler(health.NewRegistry(time.Second)))
+	RegisterDocsRoutes(s.router, docsHandler)
+	// /metrics は main.go で Prometheus のハンドラーを登録する
+	s.router.GET("/metrics", func(c *gin.Context) {
+		c.String(http.StatusOK, "# metrics\n")
+	})
+	return s
+}
+
+func TestOpenAPI_ValidDocument(t *testing.T) {
+	schemaFile, err := os.Open(oasSchemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer schemaFile.Close()
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(oasSchemaURL, schemaFile); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile(oasSchemaURL)
+	if err != nil {
+		t.Fatalf("failed to compile the OpenAPI 3.1 schema: %v", err)
+	}
+
+	c := loadContract(t)
+	if err := schema.Validate(c.document); err != nil {
+		t.Errorf("spec is not a valid OpenAPI 3.1 document: %v", err)
+	}
+	if c.document["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v", c.document["openapi"])
+	}
+}
+
+func TestOpenAPI_CoversAllRoutes(t *testing.T) {
+	s := newContractServer(t)
+	c := loadContract(t)
+
+	var routes []string
+	for _, route := range s.router.Routes() {
+		routes = append(routes, route.Method+" "+ginPathToOpenAPI(route.Path))
+	}
+	sort.Strings(routes)
+
+	documented := map[string]bool{}
+	for _, op := range c.operations() {
+		documented[op] = true
+	}
+	registered := map[string]bool{}
+	for _, route := range routes {
+		registered[route] = true
+		if !documented[route] {
+			t.Errorf("route %s is not documented", route)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			t.Errorf("operation %s has no route", op)
+		}
+	}
+}
+
+// gin のパスパラメーター（:id）を OpenAPI の形式（{id}）に変換する
+func ginPathToOpenAPI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestOpenAPI_Responses(t *testing.T) {
+	s := newContractServer(t)
+	c := loadContract(t)
+
+	// 1. 登録とログイン
+	register := func(body interface{}) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPost, "/api/v1/users/register", "", body)
+		c.check(t, http.MethodPost, "/api/v1/users/register", rec)
+		return rec
+	}
+	if rec := register(CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro Yamada", Locale: "ja"}); rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := register(CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro Yamada"}); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate register: status = %d", rec.Code)
+	}
+	if rec := register(map[string]string{"email": "not-an-email", "password": "short"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid register: status = %d", rec.Code)
+	}
+	if rec := register("not an object"); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed register: status = %d", rec.Code)
+	}
+
+	login := func(body interface{}) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPost, "/api/v1/users/login", "", body)
+		c.check(t, http.MethodPost, "/api/v1/users/login", rec)
+		return rec
+	}
+	if rec := login(LoginRequest{Email: "taro@example.com", Password: "WrongPassword1"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d", rec.Code)
+	}
+	if rec := login(map[string]string{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty login: status = %d", rec.Code)
+	}
+	rec := login(LoginRequest{Email: "taro@example.com", Password: testPassword})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var loginRes LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &loginRes); err != nil {
+		t.Fatal(err)
+	}
+	token, user := loginRes.Token, loginRes.User
+
+	// 確認コードを要求していない場合は確認できない
+	verify := func(body interface{}) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPost, "/api/v1/users/login/verify", "", body)
+		c.check(t, http.MethodPost, "/api/v1/users/login/verify", rec)
+		return rec
+	}
+	if rec := verify(VerifyLoginRequest{Email: "taro@example.com", Password: testPassword, Code: "123456"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("verify without challenge: status = %d", rec.Code)
+	}
+	if rec := verify(VerifyLoginRequest{Email: "taro@example.com", Password: testPassword, Code: "12ab"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid verification code format: status = %d", rec.Code)
+	}
+
+	// 2. プロフィール
+	profile := func(method, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+		rec := s.do(method, "/api/v1/users/profile", token, body, headers...)
+		c.check(t, method, "/api/v1/users/profile", rec)
+		return rec
+	}
+	rec = profile(http.MethodGet, token, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("get profile: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodGet, token, nil, "If-None-Match", rec.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Errorf("conditional get profile: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodGet, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous get profile: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodPut, token, map[string]string{"name": "Hanako Yamada"}, "If-Match", `"1"`); rec.Code != http.StatusOK {
+		t.Errorf("update profile: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodPut, token, map[string]string{"name": "Jiro Yamada"}, "If-Match", `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale update profile: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodPut, token, map[string]string{"locale": "fr"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid update profile: status = %d", rec.Code)
+	}
+
+	// 3. ユーザー一覧（users:read スコープが必要）
+	listUsers := func(token, query string) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodGet, "/api/v1/users"+query, token, nil)
+		c.check(t, http.MethodGet, "/api/v1/users", rec)
+		return rec
+	}
+	admin, err := s.jwtService.GenerateToken(user.ID, user.Email, auth.ScopeUsersRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := listUsers(admin, "?limit=1"); rec.Code != http.StatusOK {
+		t.Errorf("list users: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := listUsers(admin, "?sort=password"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid list users: status = %d", rec.Code)
+	}
+	if rec := listUsers(token, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("list users without scope: status = %d", rec.Code)
+	}
+
+	// 4. トークンの更新と再認証
+	rec = s.do(http.MethodPost, "/api/v1/users/refresh-token", token, nil)
+	c.check(t, http.MethodPost, "/api/v1/users/refresh-token", rec)
+	if rec.Code != http.StatusOK {
+		t.Errorf("refresh token: status = %d", rec.Code)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/users/refresh-token", "invalid", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh invalid token: status = %d", rec.Code)
+	} else {
+		c.check(t, http.MethodPost, "/api/v1/users/refresh-token", rec)
+	}
+
+	stale := s.staleToken(t, user)
+	reauthenticate := func(password string) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPost, "/api/v1/users/reauthenticate", stale, ReauthenticateRequest{Password: password})
+		c.check(t, http.MethodPost, "/api/v1/users/reauthenticate", rec)
+		return rec
+	}
+	if rec := reauthenticate("WrongPassword1"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reauthenticate with wrong password: status = %d", rec.Code)
+	}
+	rec = reauthenticate(testPassword)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reauthenticate: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var reauthRes LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &reauthRes); err != nil {
+		t.Fatal(err)
+	}
+	fresh := reauthRes.Token
+
+	// 5. パスワード・メールアドレスの変更と退会（最近の再認証が必要）
+	changePassword := func(token string, body interface{}) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPut, "/api/v1/users/password", token, body)
+		c.check(t, http.MethodPut, "/api/v1/users/password", rec)
+		return rec
+	}
+	if rec := changePassword(stale, ChangePasswordRequest{NewPassword: "NewPassword123"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("change password without reauthentication: status = %d", rec.Code)
+	}
+	if rec := changePassword(fresh, ChangePasswordRequest{NewPassword: "short"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("change password to a short one: status = %d", rec.Code)
+	}
+	if rec := changePassword(fresh, ChangePasswordRequest{NewPassword: "NewPassword123"}); rec.Code != http.StatusNoContent {
+		t.Errorf("change password: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	changeEmail := func(token string, body interface{}) *httptest.ResponseRecorder {
+		rec := s.do(http.MethodPut, "/api/v1/users/email", token, body)
+		c.check(t, http.MethodPut, "/api/v1/users/email", rec)
+		return rec
+	}
+	if rec := changeEmail(stale, ChangeEmailRequest{NewEmail: "taro.yamada@example.com"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("change email without reauthentication: status = %d", rec.Code)
+	}
+	if rec := changeEmail(fresh, ChangeEmailRequest{NewEmail: "not-an-email"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("change email to an invalid one: status = %d", rec.Code)
+	}
+	if rec := changeEmail(fresh, ChangeEmailRequest{NewEmail: "taro.yamada@example.com"}); rec.Code != http.StatusOK {
+		t.Errorf("change email: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	if rec := profile(http.MethodDelete, stale, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("delete without reauthentication: status = %d", rec.Code)
+	}
+	if rec := profile(http.MethodDelete, fresh, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := profile(http.MethodGet, token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get deleted profile: status = %d", rec.Code)
+	}
+
+	// 6. ヘルスチェック・メトリクス・API ドキュメント
+	for _, path := range []string{"/health/live", "/health/ready", "/metrics", "/openapi.yaml", "/openapi.json", "/docs"} {
+		rec := s.do(http.MethodGet, path, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d", path, rec.Code)
+		}
+		c.check(t, http.MethodGet, path, rec)
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	s := newContractServer(t)
+
+	rec := s.do(http.MethodGet, "/openapi.json", "", nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &document); err != nil {
+		t.Fatalf("invalid JSON spec: %v", err)
+	}
+	if _, ok := document["paths"].(map[string]interface{})["/api/v1/users/login"]; !ok {
+		t.Error("JSON spec does not contain /api/v1/users/login")
+	}
+
+	rec = s.do(http.MethodGet, "/openapi.yaml", "", nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/yaml" || !bytes.Equal(rec.Body.Bytes(), api.OpenAPI) {
+		t.Errorf("YAML spec: Content-Type = %q, %d bytes", got, rec.Body.Len())
+	}
+
+	rec = s.do(http.MethodGet, "/docs", "", nil)
+	if !strings.Contains(rec.Body.String(), `url: "/openapi.json"`) {
+		t.Errorf("docs page does not load /openapi.json:\n%s", rec.Body)
+	}
+	for _, want := range []string{
+		`href="https://cdn.example.com/swagger-ui-dist@5.17.14/swagger-ui.css" integrity="sha384-css" crossorigin="anonymous"`,
+		`src="https://cdn.example.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js" integrity="sha384-js" crossorigin="anonymous"`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("docs page does not contain %s", want)
+		}
+	}
+
+	// Subresource Integrity がない場合は画面を公開しない
+	router := gin.New()
+	docsHandler, err := NewDocsHandler(api.OpenAPI, DocsUIConfig{AssetsURL: testDocsUI.AssetsURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterDocsRoutes(router, docsHandler)
+	for path, want := range map[string]int{"/openapi.json": http.StatusOK, "/docs": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s without integrity: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	if _, err := NewDocsHandler([]byte("openapi: ["), testDocsUI); err == nil {
+		t.Error("NewDocsHandler() with an invalid spec should fail")
+	}
+}
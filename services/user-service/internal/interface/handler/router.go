@@ -0,0 +1,61 @@
+// services/user-service/internal/interface/handler/router.go
+package handler
+
+import (
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ルーティングの設定値
+type RouterConfig struct {
+	// 再認証を要求する操作の猶予時間
+	StepUpMaxAge time.Duration
+	// 登録・ログイン・ログイン確認・再認証のレート制限（nil の場合は制限しない）
+	AuthRateLimit gin.HandlerFunc
+	// その他の認証済みのエンドポイントのレート制限（nil の場合は制限しない）
+	UserRateLimit gin.HandlerFunc
+}
+
+// ルーティングの設定
+func RegisterRoutes(router gin.IRouter, userHandler *UserHandler, authMiddleware *middleware.AuthMiddleware, config RouterConfig) {
+	v1 := router.Group("/api/v1")
+	{
+		users := v1.Group("/users")
+		{
+			// 認証不要のエンドポイント
+			users.POST("/register", withMiddleware(config.AuthRateLimit, userHandler.CreateUser)...)
+			users.POST("/login", withMiddleware(config.AuthRateLimit, userHandler.Login)...)
+			users.POST("/login/verify", withMiddleware(config.AuthRateLimit, userHandler.VerifyLogin)...)
+
+			// 認証が必要なエンドポイント
+			protected := users.Group("", authMiddleware.AuthRequired())
+			// パスワードを受け付けるため、他より厳しい制限をかける
+			protected.POST("/reauthenticate", withMiddleware(config.AuthRateLimit, userHandler.Reauthenticate)...)
+			if config.UserRateLimit != nil {
+				protected.Use(config.UserRateLimit)
+			}
+			{
+				protected.GET("", authMiddleware.RequireScope(auth.ScopeUsersRead), userHandler.ListUsers)
+				protected.GET("/profile", authMiddleware.RequireScope(auth.ScopeProfileRead), userHandler.GetProfile)
+				protected.PUT("/profile", authMiddleware.RequireScope(auth.ScopeProfileWrite), userHandler.UpdateProfile)
+				protected.POST("/refresh-token", authMiddleware.RefreshToken())
+
+				// 最近の再認証が必要なエンドポイント
+				protected.PUT("/password", authMiddleware.RequireRecentAuth(config.StepUpMaxAge), userHandler.ChangePassword)
+				protected.PUT("/email", authMiddleware.RequireRecentAuth(config.StepUpMaxAge), userHandler.ChangeEmail)
+				protected.DELETE("/profile", authMiddleware.RequireRecentAuth(config.StepUpMaxAge), userHandler.DeleteProfile)
+			}
+		}
+	}
+}
+
+// ミドルウェア（nil の場合は省く）を付けたハンドラーの一覧
+func withMiddleware(middleware gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	if middleware == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{middleware, handler}
+}
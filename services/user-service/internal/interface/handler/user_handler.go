@@ -2,10 +2,16 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/usecase"
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +21,8 @@ type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
 	Name     string `json:"name" binding:"required"`
+	// 省略した場合はリクエストの言語
+	Locale string `json:"locale" binding:"omitempty,oneof=ja en"`
 }
 
 // ログインリクエストの形式を定義
@@ -23,22 +31,41 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ログイン確認リクエストの形式を定義
+type VerifyLoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// 再認証リクエストの形式を定義
+type ReauthenticateRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// パスワード変更リクエストの形式を定義
+type ChangePasswordRequest struct {
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+// メールアドレス変更リクエストの形式を定義
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
 // レスポンスの形式を定義
 type UserResponse struct {
 	ID        string `json:"id"`
 	Email     string `json:"email"`
 	Name      string `json:"name"`
+	Locale    string `json:"locale,omitempty"`
 	CreatedAt string `json:"created_at"`
 }
 
-// エラーレスポンスの形式
-type ErrorResponse struct {
-	Message string `json:"message"`
-}
-
 // ハンドラー構造体
 type UserHandler struct {
 	userUseCase *usecase.UserUseCase
+	cursorCodec *query.CursorCodec
 }
 
 // LoginResponse 構造体の定義
@@ -53,27 +80,53 @@ func (h *UserHandler) Login(c *gin.Context) {
 	// 1. リクエストのバリデーション
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid request format",
-		})
+		apierror.RespondBindError(c, err)
 		return
 	}
 
 	// 2. ログイン処理の実行
-	output, err := h.userUseCase.Login(c.Request.Context(), req.Email, req.Password)
+	output, err := h.userUseCase.Login(c.Request.Context(), usecase.LoginInput{
+		Email:     req.Email,
+		Password:  req.Password,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
 	if err != nil {
-		status := http.StatusInternalServerError
-		message := "Internal server error"
+		apierror.Respond(c, err)
+		return
+	}
 
-		switch err {
-		case domain.ErrInvalidCredentials:
-			status = http.StatusUnauthorized
-			message = "Invalid email or password"
-		}
+	// 3. レスポンスの返却
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:     output.Token,
+		ExpiresAt: output.ExpiresAt,
+		User: UserResponse{
+			ID:        output.User.ID,
+			Email:     output.User.Email,
+			Name:      output.User.Name,
+			Locale:    output.User.Locale,
+			CreatedAt: output.User.CreatedAt.Format(time.RFC3339),
+		},
+	})
+}
 
-		c.JSON(status, ErrorResponse{
-			Message: message,
-		})
+// ログイン確認ハンドラー（不審なログインでメールに送った確認コードを検証する）
+func (h *UserHandler) VerifyLogin(c *gin.Context) {
+	// 1. リクエストのバリデーション
+	var req VerifyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.RespondBindError(c, err)
+		return
+	}
+
+	// 2. 確認コードの検証とログインの完了
+	output, err := h.userUseCase.VerifyLogin(c.Request.Context(), usecase.VerifyLoginInput{
+		Email:    req.Email,
+		Password: req.Password,
+		Code:     req.Code,
+	})
+	if err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
@@ -85,15 +138,17 @@ func (h *UserHandler) Login(c *gin.Context) {
 			ID:        output.User.ID,
 			Email:     output.User.Email,
 			Name:      output.User.Name,
+			Locale:    output.User.Locale,
 			CreatedAt: output.User.CreatedAt.Format(time.RFC3339),
 		},
 	})
 }
 
 // ハンドラーの作成
-func NewUserHandler(uc *usecase.UserUseCase) *UserHandler {
+func NewUserHandler(uc *usecase.UserUseCase, cursorCodec *query.CursorCodec) *UserHandler {
 	return &UserHandler{
 		userUseCase: uc,
+		cursorCodec: cursorCodec,
 	}
 }
 
@@ -102,9 +157,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	// 1. リクエストのバリデーション
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid request format",
-		})
+		apierror.RespondBindError(c, err)
 		return
 	}
 
@@ -113,30 +166,17 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		Email:    req.Email,
 		Password: req.Password,
 		Name:     req.Name,
+		Locale:   req.Locale,
+	}
+	if input.Locale == "" {
+		input.Locale = i18n.LocaleFromContext(c.Request.Context())
 	}
 
 	// 3. ユースケースの実行
 	output, err := h.userUseCase.CreateUser(c.Request.Context(), input)
 	if err != nil {
 		// エラーの種類に応じて適切なステータスコードを返す
-		switch err {
-		case domain.ErrEmailAlreadyExists:
-			c.JSON(http.StatusConflict, ErrorResponse{
-				Message: "Email already exists",
-			})
-		case domain.ErrInvalidEmail:
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Message: "Invalid email format",
-			})
-		case domain.ErrWeakPassword:
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Message: "Password does not meet security requirements",
-			})
-		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Message: "Internal server error",
-			})
-		}
+		apierror.Respond(c, err)
 		return
 	}
 
@@ -145,6 +185,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		ID:        output.ID,
 		Email:     output.Email,
 		Name:      output.Name,
+		Locale:    output.Locale,
 		CreatedAt: output.CreatedAt.Format(time.RFC3339),
 	}
 	c.JSON(http.StatusCreated, response)
@@ -155,18 +196,22 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	// コンテキストから認証済みユーザーのIDを取得
 	userID := c.GetString("userID") // authMiddlewareでセットされることを想定
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Message: "Unauthorized",
-		})
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
 		return
 	}
 
 	// ユーザー情報の取得
 	user, err := h.userUseCase.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "Failed to get user profile",
-		})
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 条件付きGET（変更がなければ本文を返さない）
+	etag := versionETag(user.Version)
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == "*" || containsETag(match, etag)) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -174,44 +219,256 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 	})
 }
 
+// ユーザー一覧ハンドラー
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	// 1. クエリ文字列の解析
+	params, err := usecase.UserListSpec.Parse(c.Request.URL.Query(), h.cursorCodec)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 2. 一覧の取得
+	output, err := h.userUseCase.ListUsers(c.Request.Context(), params)
+	if err != nil {
+		// 暗号化した列での並べ替え・部分一致など、保存先が対応していない条件は 400 になる
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 3. レスポンスの作成
+	users := make([]UserResponse, 0, len(output.Users))
+	for _, user := range output.Users {
+		users = append(users, UserResponse{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Locale:    user.Locale,
+			CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	envelope, err := query.NewEnvelope(users, params.Limit, output.Next, output.Prev, c.Request.URL, h.cursorCodec)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, envelope)
+}
+
 // プロフィール更新ハンドラー
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := c.GetString("userID")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Message: "Unauthorized",
-		})
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
 		return
 	}
 
 	var req struct {
 		Name string `json:"name" binding:"required"`
+		// 省略した場合は変更しない
+		Locale string `json:"locale" binding:"omitempty,oneof=ja en"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "Invalid request format",
-		})
+		apierror.RespondBindError(c, err)
 		return
 	}
 
+	// If-Match ヘッダーから読み込み時のバージョンを取得
+	ifMatch := c.GetHeader("If-Match")
+	var expectedVersions []int
+	if ifMatch != "" && strings.TrimSpace(ifMatch) != "*" {
+		versions, ok := parseIfMatch(ifMatch)
+		if !ok {
+			apierror.Write(c, apierror.New(http.StatusPreconditionFailed, apierror.CodePreconditionFailed, "Invalid If-Match header").
+				WithMessage("invalid_if_match", nil))
+			return
+		}
+		if len(versions) == 0 {
+			apierror.Write(c, apierror.New(http.StatusPreconditionFailed, apierror.CodePreconditionFailed, "If-Match must contain a strong ETag returned by this API").
+				WithMessage("if_match_not_strong", nil))
+			return
+		}
+		expectedVersions = versions
+	}
+
 	// ユーザー情報の更新
-	user, err := h.userUseCase.UpdateUserProfile(c.Request.Context(), userID, req.Name)
+	user, err := h.userUseCase.UpdateUserProfile(c.Request.Context(), usecase.UpdateProfileInput{
+		UserID:           userID,
+		Name:             req.Name,
+		Locale:           req.Locale,
+		ExpectedVersions: expectedVersions,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "Failed to update user profile",
-		})
+		// If-Match を指定した場合の競合は前提条件の不一致として返す
+		if errors.Is(err, domain.ErrConcurrentModification) && ifMatch != "" {
+			apierror.Write(c, apierror.New(http.StatusPreconditionFailed, apierror.CodePreconditionFailed, "Profile has been modified by another request").
+				WithMessage("profile_modified", nil))
+			return
+		}
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(user.Version))
+	c.JSON(http.StatusOK, UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Locale:    user.Locale,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+// 再認証ハンドラー（現在のセッションを再認証済みのトークンに更新する）
+func (h *UserHandler) Reauthenticate(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
+		return
+	}
+
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.RespondBindError(c, err)
+		return
+	}
+
+	output, err := h.userUseCase.Reauthenticate(c.Request.Context(), usecase.ReauthenticateInput{
+		UserID:   userID,
+		Password: req.Password,
+		Scopes:   c.GetStringSlice("scopes"),
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid password").
+				WithMessage("invalid_password", nil))
+			return
+		}
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:     output.Token,
+		ExpiresAt: output.ExpiresAt,
+		User: UserResponse{
+			ID:        output.User.ID,
+			Email:     output.User.Email,
+			Name:      output.User.Name,
+			Locale:    output.User.Locale,
+			CreatedAt: output.User.CreatedAt.Format(time.RFC3339),
+		},
+	})
+}
+
+// パスワード変更ハンドラー
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
 		return
 	}
 
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.RespondBindError(c, err)
+		return
+	}
+
+	if err := h.userUseCase.ChangePassword(c.Request.Context(), userID, req.NewPassword); err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// メールアドレス変更ハンドラー
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.RespondBindError(c, err)
+		return
+	}
+
+	user, err := h.userUseCase.ChangeEmail(c.Request.Context(), userID, req.NewEmail)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(user.Version))
 	c.JSON(http.StatusOK, UserResponse{
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 	})
 }
+
+// アカウント削除ハンドラー
+func (h *UserHandler) DeleteProfile(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		apierror.Write(c, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Authentication required"))
+		return
+	}
+
+	if err := h.userUseCase.DeleteUser(c.Request.Context(), userID); err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// バージョンからETagを作成
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// If-Match のETag一覧から候補のバージョンを取得（書式が正しくない場合は false）
+// RFC 7232 の強い比較を行うため、弱いETagとこのAPIが発行していないETagは含めない
+func parseIfMatch(header string) ([]int, bool) {
+	var versions []int
+	for _, candidate := range strings.Split(header, ",") {
+		etag := strings.TrimSpace(candidate)
+		weak := strings.HasPrefix(etag, "W/")
+		etag = strings.TrimPrefix(etag, "W/")
+		if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+			return nil, false
+		}
+		if weak {
+			continue
+		}
+		if version, err := strconv.Atoi(etag[1 : len(etag)-1]); err == nil && version >= 1 {
+			versions = append(versions, version)
+		}
+	}
+	return versions, true
+}
+
+// カンマ区切りのETag一覧に指定したETagが含まれるか
+// If-None-Match 用のため RFC 7232 の弱い比較を行う
+func containsETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,554 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/apierror"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/database"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/geo"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/middleware"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/notification"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+const testPassword = "Password123"
+
+// SQLiteを使ってサービス全体を組み立てたテスト用サーバー
+type testServer struct {
+	router     *gin.Engine
+	jwtService *auth.JWTService
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewSQLiteDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	if err := persistence.AutoMigrateModels(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	jwtService := auth.NewJWTService(auth.Config{
+		SecretKey:     "test-secret",
+		Expires:       time.Hour,
+		DefaultScopes: []string{auth.ScopeProfileRead, auth.ScopeProfileWrite},
+	})
+	loginMonitor := usecase.NewLoginMonitor(
+		persistence.NewLoginEventRepository(db),
+		persistence.NewLoginChallengeRepository(db),
+		geo.NewNoopLocator(),
+		notification.NewLogMailer(),
+		usecase.LoginMonitorConfig{},
+	)
+	userUseCase := usecase.NewUserUseCase(
+		persistence.NewUserRepository(db),
+		persistence.NewOutboxRepository(db),
+		persistence.NewTxManager(db),
+		jwtService,
+		loginMonitor,
+	)
+
+	router := gin.New()
+	router.Use(middleware.Locale(i18n.English))
+	RegisterRoutes(router, NewUserHandler(userUseCase, query.NewCursorCodec([]byte("test-secret"))), middleware.NewAuthMiddleware(jwtService), RouterConfig{
+		StepUpMaxAge: 5 * time.Minute,
+	})
+
+	return &testServer{router: router, jwtService: jwtService}
+}
+
+// リクエストを送信する（body が nil の場合は本文なし）
+func (s *testServer) do(method, path, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// ユーザーを登録してログインし、トークンを返す
+func (s *testServer) registerAndLogin(t *testing.T, email string) (string, UserResponse) {
+	t.Helper()
+
+	rec := s.do(http.MethodPost, "/api/v1/users/register", "", CreateUserRequest{Email: email, Password: testPassword, Name: "Taro Yamada"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec = s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: email, Password: testPassword})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var res LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Token, res.User
+}
+
+// 認証時刻が古いトークンを発行する
+func (s *testServer) staleToken(t *testing.T, user UserResponse) string {
+	t.Helper()
+	token, err := s.jwtService.IssueToken(auth.TokenParams{
+		UserID:   user.ID,
+		Email:    user.Email,
+		AuthTime: time.Now().Add(-time.Hour),
+		ACR:      auth.ACRPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid", body: CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro"}, wantStatus: http.StatusCreated},
+		{name: "duplicate email", body: CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro"}, wantStatus: http.StatusConflict, wantCode: apierror.CodeEmailAlreadyExists},
+		{name: "weak password", body: CreateUserRequest{Email: "hanako@example.com", Password: "password", Name: "Hanako"}, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeWeakPassword},
+		{name: "missing name", body: map[string]string{"email": "jiro@example.com", "password": testPassword}, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeValidationFailed},
+		{name: "malformed json", body: "not an object", wantStatus: http.StatusBadRequest, wantCode: apierror.CodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/api/v1/users/register", "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				if p := decodeProblem(t, rec); p.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", p.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+
+	// 入力項目ごとのエラーを JSON のフィールド名で返す
+	rec := s.do(http.MethodPost, "/api/v1/users/register", "", map[string]string{"email": "jiro@example.com", "password": testPassword})
+	p := decodeProblem(t, rec)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "name" || p.Errors[0].Code != "required" {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+}
+
+// problem+json のレスポンスを読み込む
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) apierror.Problem {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, apierror.ContentType) {
+		t.Errorf("Content-Type = %q, want %s", got, apierror.ContentType)
+	}
+	var p apierror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid problem body %s: %v", rec.Body, err)
+	}
+	return p
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.registerAndLogin(t, "taro@example.com")
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "valid credentials", body: LoginRequest{Email: "taro@example.com", Password: testPassword}, wantStatus: http.StatusOK},
+		{name: "wrong password", body: LoginRequest{Email: "taro@example.com", Password: "Wrong12345"}, wantStatus: http.StatusUnauthorized},
+		{name: "unknown email", body: LoginRequest{Email: "hanako@example.com", Password: testPassword}, wantStatus: http.StatusUnauthorized},
+		{name: "missing password", body: map[string]string{"email": "taro@example.com"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/api/v1/users/login", "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.registerAndLogin(t, "taro@example.com")
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "no pending verification", body: VerifyLoginRequest{Email: "taro@example.com", Password: testPassword, Code: "123456"}, wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeInvalidVerificationCode},
+		{name: "wrong password", body: VerifyLoginRequest{Email: "taro@example.com", Password: "Wrong12345", Code: "123456"}, wantStatus: http.StatusUnauthorized, wantCode: apierror.CodeInvalidCredentials},
+		{name: "non-numeric code", body: VerifyLoginRequest{Email: "taro@example.com", Password: testPassword, Code: "12345a"}, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeValidationFailed},
+		{name: "missing code", body: map[string]string{"email": "taro@example.com", "password": testPassword}, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/api/v1/users/login/verify", "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if p := decodeProblem(t, rec); p.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", p.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	s := newTestServer(t)
+	token, user := s.registerAndLogin(t, "taro@example.com")
+
+	rec := s.do(http.MethodGet, "/api/v1/users/profile", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var res UserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != user.ID || res.Email != "taro@example.com" {
+		t.Errorf("profile = %+v", res)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Errorf("ETag = %q, want %q", etag, `"1"`)
+	}
+
+	// If-None-Match は弱い比較を行い、一覧のいずれかと一致すれば 304 を返す
+	for _, match := range []string{etag, "W/" + etag, `"5", ` + etag, "*"} {
+		if rec := s.do(http.MethodGet, "/api/v1/users/profile", token, nil, "If-None-Match", match); rec.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match %s: status = %d, want %d", match, rec.Code, http.StatusNotModified)
+		}
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/users/profile", token, nil, "If-None-Match", `"5"`); rec.Code != http.StatusOK {
+		t.Errorf("If-None-Match with another version: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/users/profile", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	writeOnly, err := s.jwtService.GenerateToken(user.ID, user.Email, auth.ScopeProfileWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := s.do(http.MethodGet, "/api/v1/users/profile", writeOnly, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("without profile:read: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.registerAndLogin(t, "taro@example.com")
+	body := map[string]string{"name": "Hanako Yamada"}
+
+	rec := s.do(http.MethodPut, "/api/v1/users/profile", token, body, "If-Match", `"1"`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Errorf("ETag = %q, want %q", got, `"2"`)
+	}
+
+	tests := []struct {
+		name       string
+		headers    []string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "stale If-Match", headers: []string{"If-Match", `"1"`}, body: body, wantStatus: http.StatusPreconditionFailed},
+		{name: "invalid If-Match", headers: []string{"If-Match", "abc"}, body: body, wantStatus: http.StatusPreconditionFailed},
+		// If-Match は強い比較のため弱いETagは一致しない（RFC 7232 §3.1）
+		{name: "weak If-Match", headers: []string{"If-Match", `W/"2"`}, body: body, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-Match list with current version", headers: []string{"If-Match", `"1", W/"2", "2"`}, body: body, wantStatus: http.StatusOK},
+		{name: "If-Match list without current version", headers: []string{"If-Match", `"1", "2", "abc"`}, body: body, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-Match any", headers: []string{"If-Match", "*"}, body: body, wantStatus: http.StatusOK},
+		{name: "unconditional", body: body, wantStatus: http.StatusOK},
+		{name: "missing name", body: map[string]string{}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodPut, "/api/v1/users/profile", token, tt.body, tt.headers...)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	s := newTestServer(t)
+	token, user := s.registerAndLogin(t, "taro@example.com")
+
+	rec := s.do(http.MethodPost, "/api/v1/users/refresh-token", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var res struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.jwtService.ValidateToken(res.Token)
+	if err != nil || claims.UserID != user.ID {
+		t.Errorf("refreshed token = %+v, %v", claims, err)
+	}
+}
+
+func TestStepUpProtectedRoutes(t *testing.T) {
+	s := newTestServer(t)
+	_, user := s.registerAndLogin(t, "taro@example.com")
+	stale := s.staleToken(t, user)
+
+	// 認証から時間が経ったセッションでは拒否される
+	if rec := s.do(http.MethodPut, "/api/v1/users/password", stale, ChangePasswordRequest{NewPassword: "NewPassword456"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("password with stale session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := s.do(http.MethodDelete, "/api/v1/users/profile", stale, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("delete with stale session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := s.do(http.MethodPut, "/api/v1/users/email", stale, ChangeEmailRequest{NewEmail: "taro.yamada@example.com"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("email with stale session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// 再認証
+	if rec := s.do(http.MethodPost, "/api/v1/users/reauthenticate", stale, ReauthenticateRequest{Password: "Wrong12345"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reauthenticate with wrong password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec := s.do(http.MethodPost, "/api/v1/users/reauthenticate", stale, ReauthenticateRequest{Password: testPassword})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reauthenticate: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var res LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	fresh := res.Token
+
+	// 再認証後は操作できる
+	if rec := s.do(http.MethodPut, "/api/v1/users/password", fresh, ChangePasswordRequest{NewPassword: "weakpassword"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("weak new password: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := s.do(http.MethodPut, "/api/v1/users/password", fresh, ChangePasswordRequest{NewPassword: "NewPassword456"}); rec.Code != http.StatusNoContent {
+		t.Fatalf("change password: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: user.Email, Password: "NewPassword456"}); rec.Code != http.StatusOK {
+		t.Errorf("login with new password: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := s.do(http.MethodPut, "/api/v1/users/email", fresh, ChangeEmailRequest{NewEmail: "not-an-email"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid new email: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = s.do(http.MethodPut, "/api/v1/users/email", fresh, ChangeEmailRequest{NewEmail: "taro.yamada@example.com"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("change email: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("ETag"); got != `"3"` {
+		t.Errorf("ETag = %q, want %q", got, `"3"`)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: "taro.yamada@example.com", Password: "NewPassword456"}); rec.Code != http.StatusOK {
+		t.Errorf("login with new email: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if rec := s.do(http.MethodDelete, "/api/v1/users/profile", fresh, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(http.MethodDelete, "/api/v1/users/profile", fresh, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: "taro.yamada@example.com", Password: "NewPassword456"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login after delete: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	s := newTestServer(t)
+	_, admin := s.registerAndLogin(t, "admin@example.com")
+	for _, email := range []string{"taro@example.com", "hanako@example.com"} {
+		if rec := s.do(http.MethodPost, "/api/v1/users/register", "", CreateUserRequest{Email: email, Password: testPassword, Name: "User"}); rec.Code != http.StatusCreated {
+			t.Fatalf("register %s: status = %d", email, rec.Code)
+		}
+	}
+
+	token, err := s.jwtService.GenerateToken(admin.ID, admin.Email, auth.ScopeUsersRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 次のページのリンクをたどって全件取得する
+	var emails []string
+	path := "/api/v1/users?limit=2&sort=email"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		rec := s.do(http.MethodGet, path, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", path, rec.Code, rec.Body)
+		}
+		var res struct {
+			Data       []UserResponse `json:"data"`
+			Pagination struct {
+				Limit int `json:"limit"`
+			} `json:"pagination"`
+			Links struct {
+				Self string `json:"self"`
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Pagination.Limit != 2 || res.Links.Self != path {
+			t.Errorf("pagination = %+v, links = %+v", res.Pagination, res.Links)
+		}
+		for _, user := range res.Data {
+			emails = append(emails, user.Email)
+		}
+		path = res.Links.Next
+	}
+
+	want := []string{"admin@example.com", "hanako@example.com", "taro@example.com"}
+	if len(emails) != len(want) {
+		t.Fatalf("emails = %v, want %v", emails, want)
+	}
+	for i := range want {
+		if emails[i] != want[i] {
+			t.Errorf("emails = %v, want %v", emails, want)
+			break
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "filter", path: "/api/v1/users?email[prefix]=taro", token: token, wantStatus: http.StatusOK},
+		{name: "invalid limit", path: "/api/v1/users?limit=0", token: token, wantStatus: http.StatusBadRequest},
+		{name: "unknown filter", path: "/api/v1/users?password=x", token: token, wantStatus: http.StatusBadRequest},
+		{name: "tampered cursor", path: "/api/v1/users?cursor=abc.def", token: token, wantStatus: http.StatusBadRequest},
+		{name: "missing scope", path: "/api/v1/users", token: s.staleToken(t, admin), wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(http.MethodGet, tt.path, tt.token, nil)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	s := newTestServer(t)
+	s.registerAndLogin(t, "taro@example.com")
+
+	// 1. Accept-Language に従って翻訳する
+	rec := s.do(http.MethodPost, "/api/v1/users/register", "", CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro"}, "Accept-Language", "ja-JP,ja;q=0.9")
+	p := decodeProblem(t, rec)
+	if p.Code != apierror.CodeEmailAlreadyExists || p.Detail != "このメールアドレスは既に登録されています" {
+		t.Errorf("problem = %+v", p)
+	}
+	if got := rec.Header().Get("Content-Language"); got != i18n.Japanese {
+		t.Errorf("Content-Language = %q", got)
+	}
+
+	// 2. 入力項目ごとのメッセージも翻訳する
+	rec = s.do(http.MethodPost, "/api/v1/users/login", "", map[string]string{"email": "taro@example.com"}, "Accept-Language", "ja")
+	p = decodeProblem(t, rec)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "password" || p.Errors[0].Message != "必須です" {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+
+	// 3. 対応していない言語は既定の言語で返す
+	rec = s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: "taro@example.com", Password: "Wrong12345"}, "Accept-Language", "fr")
+	if p := decodeProblem(t, rec); p.Detail != "Invalid email or password" {
+		t.Errorf("detail = %q", p.Detail)
+	}
+}
+
+func TestUserLocalePreference(t *testing.T) {
+	s := newTestServer(t)
+
+	// 1. 登録時の Accept-Language を希望する言語として保存する
+	rec := s.do(http.MethodPost, "/api/v1/users/register", "", CreateUserRequest{Email: "taro@example.com", Password: testPassword, Name: "Taro"}, "Accept-Language", "ja")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var user UserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Locale != i18n.Japanese {
+		t.Errorf("locale = %q, want %q", user.Locale, i18n.Japanese)
+	}
+
+	// 2. ログイン後は Accept-Language より希望する言語を優先する
+	rec = s.do(http.MethodPost, "/api/v1/users/login", "", LoginRequest{Email: "taro@example.com", Password: testPassword})
+	var login LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	rec = s.do(http.MethodPut, "/api/v1/users/password", login.Token, ChangePasswordRequest{NewPassword: "short"}, "Accept-Language", "en")
+	if p := decodeProblem(t, rec); len(p.Errors) != 1 || p.Errors[0].Message != "8文字以上で入力してください" {
+		t.Errorf("errors = %+v", p.Errors)
+	}
+
+	// 3. プロフィールの更新で変更できる（対応していない言語は拒否する）
+	rec = s.do(http.MethodPut, "/api/v1/users/profile", login.Token, map[string]string{"name": "Taro", "locale": "fr"})
+	if p := decodeProblem(t, rec); rec.Code != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "locale" {
+		t.Errorf("status = %d, errors = %+v", rec.Code, p.Errors)
+	}
+	rec = s.do(http.MethodPut, "/api/v1/users/profile", login.Token, map[string]string{"name": "Taro", "locale": "en"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil || user.Locale != i18n.English {
+		t.Errorf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
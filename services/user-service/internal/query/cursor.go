@@ -0,0 +1,83 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ページをたどる方向
+type Direction string
+
+const (
+	DirectionNext Direction = "next"
+	DirectionPrev Direction = "prev"
+)
+
+// ページ位置を表すカーソル
+// クライアントには署名付きの不透明な文字列として渡す
+type Cursor struct {
+	// 境界となる要素の並び順キーの値
+	Value string `json:"v"`
+	// 境界となる要素のID（並び順キーが同じ要素の順序を決める）
+	ID        string    `json:"id"`
+	Direction Direction `json:"d"`
+	// 発行時の並び順と絞り込み条件（異なる条件での再利用を防ぐ）
+	Fingerprint string `json:"f"`
+}
+
+// カーソルの符号化・署名検証
+type CursorCodec struct {
+	key []byte
+}
+
+// カーソルの符号化に使うコーデックを作成する関数
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{key: secret}
+}
+
+// カーソルを "<payload>.<signature>" 形式の文字列にする
+func (c *CursorCodec) Encode(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// 文字列からカーソルを復元する（署名が一致しない場合は ErrInvalidCursor）
+func (c *CursorCodec) Decode(token string) (*Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Direction != DirectionNext && cursor.Direction != DirectionPrev {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (c *CursorCodec) sign(payload string) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// 絞り込みの演算子
+type Operator string
+
+const (
+	OpEq       Operator = "eq"
+	OpNe       Operator = "ne"
+	OpLt       Operator = "lt"
+	OpLte      Operator = "lte"
+	OpGt       Operator = "gt"
+	OpGte      Operator = "gte"
+	OpContains Operator = "contains"
+	OpPrefix   Operator = "prefix"
+)
+
+// 絞り込みの値の型
+type ValueType int
+
+const (
+	TypeString ValueType = iota
+	// RFC 3339 形式の日時
+	TypeTime
+)
+
+// 絞り込みに使えるフィールドの定義
+type FilterField struct {
+	Name      string
+	Type      ValueType
+	Operators []Operator
+}
+
+func (f FilterField) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// 絞り込み条件
+type Condition struct {
+	Field    string
+	Operator Operator
+	// TypeString の場合は string、TypeTime の場合は time.Time
+	Value interface{}
+}
+
+func (c Condition) String() string {
+	value := c.Value
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s[%s]=%v", c.Field, c.Operator, value)
+}
+
+// クエリ文字列から絞り込み条件を取得する
+// "name=Taro" は eq、"created_at[gte]=2024-01-01T00:00:00Z" のように演算子を指定できる
+// reserved に含まれるパラメーター（limit など）は無視する
+func ParseFilters(values url.Values, fields []FilterField, reserved ...string) ([]Condition, error) {
+	skip := make(map[string]bool, len(reserved))
+	for _, r := range reserved {
+		skip[r] = true
+	}
+	byName := make(map[string]FilterField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+
+	// 条件の順序を安定させるためキーを並べ替える
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	for _, key := range keys {
+		if skip[key] {
+			continue
+		}
+
+		name, op := key, OpEq
+		if i := strings.IndexByte(key, '['); i >= 0 && strings.HasSuffix(key, "]") {
+			name, op = key[:i], Operator(key[i+1:len(key)-1])
+		}
+
+		field, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, name)
+		}
+		if !field.allows(op) {
+			return nil, fmt.Errorf("%w: operator %q is not supported for %q", ErrInvalidFilter, op, name)
+		}
+
+		for _, raw := range values[key] {
+			value, err := parseValue(field.Type, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, key, err)
+			}
+			conditions = append(conditions, Condition{Field: name, Operator: op, Value: value})
+		}
+	}
+	return conditions, nil
+}
+
+func parseValue(valueType ValueType, raw string) (interface{}, error) {
+	switch valueType {
+	case TypeTime:
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected RFC 3339 time")
+		}
+		return t, nil
+	default:
+		if raw == "" {
+			return nil, fmt.Errorf("empty value")
+		}
+		return raw, nil
+	}
+}
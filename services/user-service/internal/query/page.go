@@ -0,0 +1,110 @@
+package query
+
+import (
+	"net/url"
+)
+
+// 1ページ分の結果
+type Page[T any] struct {
+	Items []T
+	// 次・前のページがない場合は nil
+	Next *Cursor
+	Prev *Cursor
+}
+
+// 最大 Limit+1 件の取得結果から1ページを作る
+// 前方向に取得した結果は逆順に並んでいるものとして並べ直す
+// key は要素の並び順キーの値とIDを返す
+func NewPage[T any](items []T, params Params, key func(T) (value, id string)) Page[T] {
+	hasMore := len(items) > params.Limit
+	if hasMore {
+		items = items[:params.Limit]
+	}
+
+	direction := params.Direction()
+	if direction == DirectionPrev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := Page[T]{Items: items}
+	if len(items) == 0 {
+		return page
+	}
+
+	// 前方向に進んでいる場合、元のページ（後ろ）は必ず存在する
+	hasNext := hasMore || direction == DirectionPrev
+	hasPrev := (direction == DirectionNext && params.Cursor != nil) || (direction == DirectionPrev && hasMore)
+
+	fingerprint := params.Fingerprint()
+	if hasNext {
+		value, id := key(items[len(items)-1])
+		page.Next = &Cursor{Value: value, ID: id, Direction: DirectionNext, Fingerprint: fingerprint}
+	}
+	if hasPrev {
+		value, id := key(items[0])
+		page.Prev = &Cursor{Value: value, ID: id, Direction: DirectionPrev, Fingerprint: fingerprint}
+	}
+	return page
+}
+
+// ページ情報
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// 現在・次・前のページのURL
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// 一覧レスポンスの形式
+type Envelope[T any] struct {
+	Data       []T      `json:"data"`
+	Pagination PageInfo `json:"pagination"`
+	Links      Links    `json:"links"`
+}
+
+// 一覧レスポンスを作成する
+// リンクはリクエストのURLのカーソルだけを置き換えて作る
+func NewEnvelope[T any](data []T, limit int, next, prev *Cursor, requestURL *url.URL, codec *CursorCodec) (*Envelope[T], error) {
+	if data == nil {
+		data = []T{}
+	}
+	envelope := &Envelope[T]{
+		Data:       data,
+		Pagination: PageInfo{Limit: limit},
+		Links:      Links{Self: requestURL.RequestURI()},
+	}
+
+	if next != nil {
+		token, err := codec.Encode(*next)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Pagination.NextCursor = token
+		envelope.Links.Next = withCursor(requestURL, token)
+	}
+	if prev != nil {
+		token, err := codec.Encode(*prev)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Pagination.PrevCursor = token
+		envelope.Links.Prev = withCursor(requestURL, token)
+	}
+	return envelope, nil
+}
+
+func withCursor(requestURL *url.URL, token string) string {
+	u := *requestURL
+	values := u.Query()
+	values.Set(ParamCursor, token)
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
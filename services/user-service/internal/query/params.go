@@ -0,0 +1,131 @@
+package query
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// クエリ文字列のうち絞り込み以外に使うパラメーター
+const (
+	ParamLimit  = "limit"
+	ParamCursor = "cursor"
+	ParamSort   = "sort"
+)
+
+var (
+	ErrInvalidLimit = errors.New("invalid limit")
+	ErrInvalidSort  = errors.New("invalid sort")
+)
+
+// 並び順（同じ値の要素はIDの順に並べる）
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// "created_at" または降順の "-created_at" 形式
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// 一覧取得で受け付けるパラメーターの定義
+type Spec struct {
+	DefaultLimit int
+	MaxLimit     int
+	// 並び順に使えるフィールド
+	SortFields  []string
+	DefaultSort Sort
+	Filters     []FilterField
+}
+
+// 一覧取得の条件
+type Params struct {
+	Limit   int
+	Sort    Sort
+	Filters []Condition
+	// 最初のページの場合は nil
+	Cursor *Cursor
+}
+
+// 取得方向（カーソルがない場合は先頭から）
+func (p Params) Direction() Direction {
+	if p.Cursor == nil {
+		return DirectionNext
+	}
+	return p.Cursor.Direction
+}
+
+// 並び順と絞り込み条件を識別する値
+func (p Params) Fingerprint() string {
+	parts := make([]string, 0, len(p.Filters)+1)
+	parts = append(parts, p.Sort.String())
+	for _, c := range p.Filters {
+		parts = append(parts, c.String())
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "&")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// クエリ文字列から一覧取得の条件を作成する
+func (s Spec) Parse(values url.Values, codec *CursorCodec) (Params, error) {
+	params := Params{
+		Limit: s.DefaultLimit,
+		Sort:  s.DefaultSort,
+	}
+
+	// 1. 取得件数
+	if raw := values.Get(ParamLimit); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > s.MaxLimit {
+			return Params{}, fmt.Errorf("%w: must be between 1 and %d", ErrInvalidLimit, s.MaxLimit)
+		}
+		params.Limit = limit
+	}
+
+	// 2. 並び順
+	if raw := values.Get(ParamSort); raw != "" {
+		sort := Sort{Field: strings.TrimPrefix(raw, "-"), Desc: strings.HasPrefix(raw, "-")}
+		if !contains(s.SortFields, sort.Field) {
+			return Params{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, sort.Field)
+		}
+		params.Sort = sort
+	}
+
+	// 3. 絞り込み条件
+	filters, err := ParseFilters(values, s.Filters, ParamLimit, ParamCursor, ParamSort)
+	if err != nil {
+		return Params{}, err
+	}
+	params.Filters = filters
+
+	// 4. カーソル（発行時と条件が異なる場合は使えない）
+	if raw := values.Get(ParamCursor); raw != "" {
+		cursor, err := codec.Decode(raw)
+		if err != nil {
+			return Params{}, err
+		}
+		if cursor.Fingerprint != params.Fingerprint() {
+			return Params{}, fmt.Errorf("%w: sort or filters changed", ErrInvalidCursor)
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSpec = Spec{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+	SortFields:   []string{"created_at", "name"},
+	DefaultSort:  Sort{Field: "created_at"},
+	Filters: []FilterField{
+		{Name: "name", Type: TypeString, Operators: []Operator{OpEq, OpContains}},
+		{Name: "created_at", Type: TypeTime, Operators: []Operator{OpGte, OpLt}},
+	},
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	cursor := Cursor{Value: "2024-01-01T00:00:00Z", ID: "user-1", Direction: DirectionNext, Fingerprint: "abc"}
+
+	token, err := codec.Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := codec.Decode(token)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if *got != cursor {
+		t.Errorf("Decode() = %+v, want %+v", *got, cursor)
+	}
+
+	other, _ := NewCursorCodec([]byte("other")).Encode(cursor)
+	payload, _, _ := strings.Cut(token, ".")
+	_, signature, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "signed with another key", token: other},
+		{name: "tampered payload", token: payload + "x." + strings.SplitN(token, ".", 2)[1]},
+		{name: "foreign signature", token: payload + "." + signature},
+		{name: "missing signature", token: payload},
+		{name: "garbage", token: "!!!.???"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Decode(tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestSpec_Parse(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	tests := []struct {
+		name    string
+		query   string
+		want    func(t *testing.T, p Params)
+		wantErr error
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want: func(t *testing.T, p Params) {
+				if p.Limit != 20 || p.Sort != (Sort{Field: "created_at"}) || len(p.Filters) != 0 || p.Cursor != nil {
+					t.Errorf("Params = %+v", p)
+				}
+			},
+		},
+		{
+			name:  "limit, sort and filters",
+			query: "limit=5&sort=-name&name[contains]=taro&created_at[gte]=2024-01-01T00:00:00Z",
+			want: func(t *testing.T, p Params) {
+				if p.Limit != 5 || p.Sort != (Sort{Field: "name", Desc: true}) {
+					t.Errorf("Params = %+v", p)
+				}
+				if len(p.Filters) != 2 {
+					t.Fatalf("Filters = %v", p.Filters)
+				}
+				if p.Filters[0].Field != "created_at" || p.Filters[0].Operator != OpGte {
+					t.Errorf("Filters[0] = %+v", p.Filters[0])
+				}
+				if _, ok := p.Filters[0].Value.(time.Time); !ok {
+					t.Errorf("created_at value = %T, want time.Time", p.Filters[0].Value)
+				}
+				if p.Filters[1] != (Condition{Field: "name", Operator: OpContains, Value: "taro"}) {
+					t.Errorf("Filters[1] = %+v", p.Filters[1])
+				}
+			},
+		},
+		{name: "limit too large", query: "limit=101", wantErr: ErrInvalidLimit},
+		{name: "limit not a number", query: "limit=ten", wantErr: ErrInvalidLimit},
+		{name: "unknown sort field", query: "sort=password", wantErr: ErrInvalidSort},
+		{name: "unknown filter field", query: "password=secret", wantErr: ErrInvalidFilter},
+		{name: "unsupported operator", query: "name[gt]=a", wantErr: ErrInvalidFilter},
+		{name: "invalid time", query: "created_at[gte]=yesterday", wantErr: ErrInvalidFilter},
+		{name: "empty string", query: "name=", wantErr: ErrInvalidFilter},
+		{name: "invalid cursor", query: "cursor=abc", wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			params, err := testSpec.Parse(values, codec)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				tt.want(t, params)
+			}
+		})
+	}
+
+	t.Run("cursor bound to sort and filters", func(t *testing.T) {
+		values, _ := url.ParseQuery("name=taro")
+		params, err := testSpec.Parse(values, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := codec.Encode(Cursor{Value: "v", ID: "1", Direction: DirectionNext, Fingerprint: params.Fingerprint()})
+
+		values.Set(ParamCursor, token)
+		if params, err := testSpec.Parse(values, codec); err != nil || params.Cursor == nil {
+			t.Errorf("Parse() with matching cursor = %+v, %v", params, err)
+		}
+
+		values.Set("name", "hanako")
+		if _, err := testSpec.Parse(values, codec); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Parse() with changed filter error = %v, want %v", err, ErrInvalidCursor)
+		}
+	})
+}
+
+type item struct{ id string }
+
+func itemKey(i item) (string, string) { return i.id, i.id }
+
+func ids(items []item) string {
+	var s []string
+	for _, i := range items {
+		s = append(s, i.id)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestNewPage(t *testing.T) {
+	cursor := func(d Direction) *Cursor { return &Cursor{Direction: d} }
+
+	tests := []struct {
+		name     string
+		items    []item
+		cursor   *Cursor
+		wantIDs  string
+		wantNext string
+		wantPrev string
+	}{
+		{name: "first page with more", items: []item{{"a"}, {"b"}, {"c"}}, wantIDs: "a,b", wantNext: "b"},
+		{name: "only page", items: []item{{"a"}, {"b"}}, wantIDs: "a,b"},
+		{name: "middle page", items: []item{{"c"}, {"d"}, {"e"}}, cursor: cursor(DirectionNext), wantIDs: "c,d", wantNext: "d", wantPrev: "c"},
+		{name: "last page", items: []item{{"e"}}, cursor: cursor(DirectionNext), wantIDs: "e", wantPrev: "e"},
+		{name: "backwards with more", items: []item{{"d"}, {"c"}, {"b"}}, cursor: cursor(DirectionPrev), wantIDs: "c,d", wantNext: "d", wantPrev: "c"},
+		{name: "backwards to first", items: []item{{"b"}, {"a"}}, cursor: cursor(DirectionPrev), wantIDs: "a,b", wantNext: "b"},
+		{name: "empty", items: nil, cursor: cursor(DirectionNext), wantIDs: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(tt.items, Params{Limit: 2, Sort: Sort{Field: "id"}, Cursor: tt.cursor}, itemKey)
+
+			if got := ids(page.Items); got != tt.wantIDs {
+				t.Errorf("Items = %s, want %s", got, tt.wantIDs)
+			}
+			checkCursor(t, "Next", page.Next, tt.wantNext, DirectionNext)
+			checkCursor(t, "Prev", page.Prev, tt.wantPrev, DirectionPrev)
+		})
+	}
+}
+
+func checkCursor(t *testing.T, name string, got *Cursor, wantID string, wantDirection Direction) {
+	t.Helper()
+	if wantID == "" {
+		if got != nil {
+			t.Errorf("%s = %+v, want nil", name, got)
+		}
+		return
+	}
+	if got == nil || got.ID != wantID || got.Direction != wantDirection {
+		t.Errorf("%s = %+v, want ID %s", name, got, wantID)
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	requestURL, _ := url.Parse("/api/v1/users?limit=2&name=taro")
+	next := &Cursor{Value: "b", ID: "b", Direction: DirectionNext}
+
+	envelope, err := NewEnvelope[string](nil, 2, next, nil, requestURL, codec)
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	if envelope.Data == nil {
+		t.Error("Data is nil, want empty slice")
+	}
+	if envelope.Links.Self != "/api/v1/users?limit=2&name=taro" {
+		t.Errorf("Self = %s", envelope.Links.Self)
+	}
+	if envelope.Links.Prev != "" || envelope.Pagination.PrevCursor != "" {
+		t.Errorf("unexpected prev link: %+v", envelope)
+	}
+
+	nextURL, err := url.Parse(envelope.Links.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := nextURL.Query()
+	if values.Get("limit") != "2" || values.Get("name") != "taro" || values.Get(ParamCursor) != envelope.Pagination.NextCursor {
+		t.Errorf("Next = %s", envelope.Links.Next)
+	}
+	if cursor, err := codec.Decode(envelope.Pagination.NextCursor); err != nil || *cursor != *next {
+		t.Errorf("next cursor = %+v, %v", cursor, err)
+	}
+}
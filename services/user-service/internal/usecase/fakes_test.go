@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/notification"
+	"github.com/google/uuid"
+)
+
+// 関数をそのまま実行するトランザクション管理（テスト用）
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// 保存されたイベントを記録するアウトボックス（テスト用）
+type fakeOutboxRepository struct {
+	mu     sync.Mutex
+	events []domain.DomainEvent
+}
+
+func (r *fakeOutboxRepository) Add(_ context.Context, event domain.DomainEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeOutboxRepository) FetchPending(context.Context, int) ([]*domain.OutboxMessage, error) {
+	return nil, nil
+}
+
+func (r *fakeOutboxRepository) MarkPublished(context.Context, string) error {
+	return nil
+}
+
+func (r *fakeOutboxRepository) MarkFailed(context.Context, string, string, time.Time) error {
+	return nil
+}
+
+func (r *fakeOutboxRepository) eventTypes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.EventType())
+	}
+	return types
+}
+
+// メモリ上のログイン履歴（テスト用）
+type fakeLoginEventRepository struct {
+	mu     sync.Mutex
+	events []*domain.LoginEvent
+}
+
+func (r *fakeLoginEventRepository) Create(_ context.Context, event *domain.LoginEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = uuid.New().String()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeLoginEventRepository) FindRecentSucceededByUserID(_ context.Context, userID string, limit int) ([]*domain.LoginEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*domain.LoginEvent
+	for i := len(r.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if r.events[i].UserID == userID && r.events[i].Succeeded {
+			events = append(events, r.events[i])
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeLoginEventRepository) MarkSucceeded(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.ID == id {
+			event.Succeeded = true
+		}
+	}
+	return nil
+}
+
+// メモリ上のログイン確認（テスト用）
+type fakeLoginChallengeRepository struct {
+	mu         sync.Mutex
+	challenges map[string]*domain.LoginChallenge
+}
+
+func (r *fakeLoginChallengeRepository) Create(_ context.Context, challenge *domain.LoginChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.challenges == nil {
+		r.challenges = make(map[string]*domain.LoginChallenge)
+	}
+	challenge.ID = uuid.New().String()
+	r.challenges[challenge.UserID] = challenge
+	return nil
+}
+
+func (r *fakeLoginChallengeRepository) FindLatestByUserID(_ context.Context, userID string) (*domain.LoginChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if challenge, ok := r.challenges[userID]; ok {
+		copied := *challenge
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeLoginChallengeRepository) IncrementAttempts(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, challenge := range r.challenges {
+		if challenge.ID == id {
+			challenge.Attempts++
+		}
+	}
+	return nil
+}
+
+func (r *fakeLoginChallengeRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for userID, challenge := range r.challenges {
+		if challenge.ID == id {
+			delete(r.challenges, userID)
+		}
+	}
+	return nil
+}
+
+// 送信したメールを記録するメーラー（テスト用）
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []notification.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg notification.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// 記録したメトリクス（テスト用）
+type fakeMetrics struct {
+	mu     sync.Mutex
+	logins []string
+	bcrypt map[string]int
+}
+
+func (m *fakeMetrics) LoginAttempt(result, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logins = append(m.logins, result+"/"+reason)
+}
+
+func (m *fakeMetrics) BcryptDuration(operation string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bcrypt == nil {
+		m.bcrypt = make(map[string]int)
+	}
+	m.bcrypt[operation]++
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/geo"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/notification"
+)
+
+// 異常検知に使うログイン履歴の件数
+const loginHistorySize = 20
+
+// 確認コードの設定
+const (
+	verificationCodeDigits  = 6
+	verificationCodeTTL     = 10 * time.Minute
+	maxVerificationAttempts = 5
+)
+
+// ログイン監視の設定
+type LoginMonitorConfig struct {
+	// 移動不可能とみなす速度（km/h）
+	MaxTravelSpeedKmh float64
+	// 不審なログインにメールで送った確認コードの入力を要求するか
+	RequireMFAOnAnomaly bool
+}
+
+// ログインの記録と異常検知
+type LoginMonitor struct {
+	eventRepo     domain.LoginEventRepository
+	challengeRepo domain.LoginChallengeRepository
+	locator       geo.Locator
+	mailer        notification.Mailer
+	config        LoginMonitorConfig
+}
+
+// ログイン監視の作成
+func NewLoginMonitor(repo domain.LoginEventRepository, challengeRepo domain.LoginChallengeRepository, locator geo.Locator, mailer notification.Mailer, config LoginMonitorConfig) *LoginMonitor {
+	return &LoginMonitor{
+		eventRepo:     repo,
+		challengeRepo: challengeRepo,
+		locator:       locator,
+		mailer:        mailer,
+		config:        config,
+	}
+}
+
+// ログインの評価（MFAが必要な場合は確認コードをメールで送り、domain.ErrMFARequired を返す）
+func (m *LoginMonitor) Evaluate(ctx context.Context, user *domain.User, ipAddress, userAgent string) error {
+	// 1. ログインイベントの作成
+	event := &domain.LoginEvent{
+		UserID:    user.ID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		DeviceID:  deviceID(userAgent),
+		Succeeded: true,
+		CreatedAt: time.Now(),
+	}
+
+	// 2. 位置情報の取得（失敗してもログインは継続する）
+	if ipAddress != "" {
+		location, err := m.locator.Lookup(ipAddress)
+		if err != nil {
+			slog.WarnContext(ctx, "geo lookup failed for login event", "error", err)
+		}
+		event.Location = location
+	}
+
+	// 3. 過去の履歴との比較（MFA要求などで完了しなかったログインは含めない）
+	history, err := m.eventRepo.FindRecentSucceededByUserID(ctx, user.ID, loginHistorySize)
+	if err != nil {
+		return err
+	}
+	event.Flags = domain.DetectLoginAnomalies(event, history, m.config.MaxTravelSpeedKmh)
+
+	requireMFA := event.Suspicious() && m.config.RequireMFAOnAnomaly
+	if requireMFA {
+		event.Succeeded = false
+	}
+
+	// 4. イベントの記録
+	if err := m.eventRepo.Create(ctx, event); err != nil {
+		return err
+	}
+
+	// 5. 不審なログインの通知（送信失敗でログインを止めない）
+	if event.Suspicious() {
+		if err := m.mailer.Send(ctx, suspiciousLoginMessage(ctx, user, event)); err != nil {
+			slog.WarnContext(ctx, "failed to send login alert", "user_id", user.ID, "error", err)
+		}
+	}
+
+	if !requireMFA {
+		return nil
+	}
+
+	// 6. 確認コードの発行と送信（コードが届かなければログインできないため、送信失敗はエラーにする）
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
+	}
+	challenge := &domain.LoginChallenge{
+		UserID:       user.ID,
+		LoginEventID: event.ID,
+		CodeHash:     hashVerificationCode(event.ID, code),
+		ExpiresAt:    event.CreatedAt.Add(verificationCodeTTL),
+		CreatedAt:    event.CreatedAt,
+	}
+	if err := m.challengeRepo.Create(ctx, challenge); err != nil {
+		return err
+	}
+	if err := m.mailer.Send(ctx, verificationCodeMessage(ctx, user, code)); err != nil {
+		return fmt.Errorf("send verification code: %w", err)
+	}
+	return domain.ErrMFARequired
+}
+
+// 確認コードの検証（成功した場合は保留していたログインを完了扱いにし、その端末を既知とする）
+// コードが誤り・期限切れ・入力回数超過の場合は domain.ErrInvalidVerificationCode を返す
+func (m *LoginMonitor) Verify(ctx context.Context, user *domain.User, code string) error {
+	// 1. 有効な確認の取得
+	challenge, err := m.challengeRepo.FindLatestByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if challenge == nil || challenge.Expired(time.Now()) || challenge.Attempts >= maxVerificationAttempts {
+		return domain.ErrInvalidVerificationCode
+	}
+
+	// 2. コードの照合（誤りの場合は入力回数を記録する）
+	hash := hashVerificationCode(challenge.LoginEventID, code)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(challenge.CodeHash)) != 1 {
+		if err := m.challengeRepo.IncrementAttempts(ctx, challenge.ID); err != nil {
+			return err
+		}
+		return domain.ErrInvalidVerificationCode
+	}
+
+	// 3. 確認の使用済み化とログインの完了
+	if err := m.challengeRepo.Delete(ctx, challenge.ID); err != nil {
+		return err
+	}
+	return m.eventRepo.MarkSucceeded(ctx, challenge.LoginEventID)
+}
+
+// ユーザーエージェント中のバージョン番号（ブラウザの更新で変わるため端末識別子に含めない）
+var userAgentVersionPattern = regexp.MustCompile(`[0-9]+(\.[0-9]+)*`)
+
+// ユーザーエージェントから端末識別子を算出
+func deviceID(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgentVersionPattern.ReplaceAllString(userAgent, "")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ランダムな確認コードの生成
+func newVerificationCode() (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(verificationCodeDigits), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n), nil
+}
+
+// 確認コードのハッシュ（ログインイベントごとに異なる値になるようIDを含める）
+func hashVerificationCode(loginEventID, code string) string {
+	sum := sha256.Sum256([]byte(loginEventID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// メールの言語（ユーザーの希望する言語、なければリクエストの言語）
+func mailLocale(ctx context.Context, user *domain.User) string {
+	if i18n.Supported(user.Locale) {
+		return user.Locale
+	}
+	return i18n.LocaleFromContext(ctx)
+}
+
+// 確認コードのメール
+func verificationCodeMessage(ctx context.Context, user *domain.User, code string) notification.Message {
+	locale := mailLocale(ctx, user)
+	t := func(id string, params i18n.Params) string {
+		return i18n.T(locale, "mail.verification_code."+id, params)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", t("greeting", i18n.Params{"name": user.Name}))
+	fmt.Fprintf(&b, "%s\n\n", t("intro", nil))
+	fmt.Fprintf(&b, "%s\n\n", t("code", i18n.Params{"code": code}))
+	fmt.Fprintf(&b, "%s\n", t("expires", i18n.Params{"minutes": fmt.Sprint(verificationCodeTTL.Minutes())}))
+	fmt.Fprintf(&b, "\n%s\n", t("action", nil))
+
+	return notification.Message{
+		To:      user.Email,
+		Subject: t("subject", nil),
+		Body:    b.String(),
+	}
+}
+
+// 不審なログインの通知メール
+func suspiciousLoginMessage(ctx context.Context, user *domain.User, event *domain.LoginEvent) notification.Message {
+	locale := mailLocale(ctx, user)
+	t := func(id string, params i18n.Params) string {
+		return i18n.T(locale, "mail.suspicious_login."+id, params)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", t("greeting", i18n.Params{"name": user.Name}))
+	fmt.Fprintf(&b, "%s\n\n", t("intro", nil))
+	fmt.Fprintf(&b, "%s\n", t("time", i18n.Params{"time": event.CreatedAt.Format(i18n.T(locale, "mail.time_format", nil))}))
+	fmt.Fprintf(&b, "%s\n", t("ip_address", i18n.Params{"ip": event.IPAddress}))
+	fmt.Fprintf(&b, "%s\n", t("device", i18n.Params{"device": event.UserAgent}))
+	if event.Location != nil {
+		fmt.Fprintf(&b, "%s\n", t("location", i18n.Params{"city": event.Location.City, "country": event.Location.Country}))
+	}
+	if event.HasFlag(domain.LoginFlagNewDevice) {
+		fmt.Fprintf(&b, "\n%s\n", t("new_device", nil))
+	}
+	if event.HasFlag(domain.LoginFlagImpossibleTravel) {
+		fmt.Fprintf(&b, "\n%s\n", t("impossible_travel", nil))
+	}
+	fmt.Fprintf(&b, "\n%s\n", t("action", nil))
+
+	return notification.Message{
+		To:      user.Email,
+		Subject: t("subject", nil),
+		Body:    b.String(),
+	}
+}
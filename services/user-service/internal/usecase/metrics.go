@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ログイン結果（メトリクスのラベル）
+const (
+	LoginResultSuccess = "success"
+	LoginResultFailure = "failure"
+)
+
+// ログイン失敗の理由（メトリクスのラベル）
+const (
+	LoginReasonNone            = "none"
+	LoginReasonUserNotFound    = "user_not_found"
+	LoginReasonInvalidPassword = "invalid_password"
+	LoginReasonMFARequired     = "mfa_required"
+	LoginReasonInvalidCode     = "invalid_verification_code"
+	LoginReasonError           = "error"
+)
+
+// bcrypt の処理（メトリクスのラベル）
+const (
+	BcryptHash    = "hash"
+	BcryptCompare = "compare"
+)
+
+// ユースケースのメトリクスの収集
+type Metrics interface {
+	LoginAttempt(result, reason string)
+	BcryptDuration(operation string, d time.Duration)
+}
+
+// 何もしない Metrics
+type noopMetrics struct{}
+
+func (noopMetrics) LoginAttempt(string, string)          {}
+func (noopMetrics) BcryptDuration(string, time.Duration) {}
+
+// メトリクスの収集先の設定
+func (uc *UserUseCase) SetMetrics(metrics Metrics) {
+	uc.metrics = metrics
+}
+
+// パスワードのハッシュ化（処理時間を記録する）
+func (uc *UserUseCase) hashPassword(password string) (string, error) {
+	start := time.Now()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	uc.metrics.BcryptDuration(BcryptHash, time.Since(start))
+	return string(hashed), err
+}
+
+// パスワードの照合（処理時間を記録する）
+func (uc *UserUseCase) comparePassword(hashed, password string) error {
+	start := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	uc.metrics.BcryptDuration(BcryptCompare, time.Since(start))
+	return err
+}
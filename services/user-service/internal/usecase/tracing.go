@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ユースケースのスパンの開始（名前は "UserUseCase.<メソッド名>"）
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "UserUseCase."+method, trace.WithAttributes(attrs...))
+}
+
+// スパンの終了（エラーがあれば記録する）
+func finishSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -2,11 +2,14 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
 	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/query"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ユースケースの入力データ
@@ -14,6 +17,34 @@ type CreateUserInput struct {
 	Email    string
 	Password string
 	Name     string
+	// メールやAPIのメッセージに使う言語
+	Locale string
+}
+
+// ログインの入力データ
+type LoginInput struct {
+	Email     string
+	Password  string
+	IPAddress string
+	UserAgent string
+}
+
+// ログイン確認の入力データ
+type VerifyLoginInput struct {
+	Email    string
+	Password string
+	// メールで送った確認コード
+	Code string
+}
+
+// プロフィール更新の入力データ
+type UpdateProfileInput struct {
+	UserID string
+	Name   string
+	// 空の場合は変更しない
+	Locale string
+	// クライアントが読み込んだバージョンの候補（空の場合は検証しない）
+	ExpectedVersions []int
 }
 
 // ユースケースの出力データ
@@ -21,20 +52,30 @@ type UserOutput struct {
 	ID        string
 	Email     string
 	Name      string
+	Locale    string
+	Version   int
 	CreatedAt time.Time
 }
 
 // ユースケース構造体
 type UserUseCase struct {
-	userRepo   domain.UserRepository
-	jwtService *auth.JWTService
+	userRepo     domain.UserRepository
+	outboxRepo   domain.OutboxRepository
+	txManager    domain.TxManager
+	jwtService   *auth.JWTService
+	loginMonitor *LoginMonitor
+	metrics      Metrics
 }
 
 // ユースケースの作成
-func NewUserUseCase(repo domain.UserRepository, jwtService *auth.JWTService) *UserUseCase {
+func NewUserUseCase(repo domain.UserRepository, outboxRepo domain.OutboxRepository, txManager domain.TxManager, jwtService *auth.JWTService, loginMonitor *LoginMonitor) *UserUseCase {
 	return &UserUseCase{
-		userRepo:   repo,
-		jwtService: jwtService,
+		userRepo:     repo,
+		outboxRepo:   outboxRepo,
+		txManager:    txManager,
+		jwtService:   jwtService,
+		loginMonitor: loginMonitor,
+		metrics:      noopMetrics{},
 	}
 }
 
@@ -46,9 +87,142 @@ type LoginOutput struct {
 }
 
 // ログイン機能の実装
-func (uc *UserUseCase) Login(ctx context.Context, email, password string) (*LoginOutput, error) {
+func (uc *UserUseCase) Login(ctx context.Context, input LoginInput) (_ *LoginOutput, err error) {
+	ctx, span := startSpan(ctx, "Login")
+	defer func() { finishSpan(span, err) }()
+
+	output, reason, err := uc.login(ctx, input)
+	if err != nil {
+		uc.metrics.LoginAttempt(LoginResultFailure, reason)
+		return nil, err
+	}
+	uc.metrics.LoginAttempt(LoginResultSuccess, LoginReasonNone)
+	return output, nil
+}
+
+// ログインの処理（失敗した場合はその理由も返す）
+func (uc *UserUseCase) login(ctx context.Context, input LoginInput) (*LoginOutput, string, error) {
+	// 1. ユーザーの検索とパスワードの検証
+	user, reason, err := uc.authenticate(ctx, input.Email, input.Password)
+	if err != nil {
+		return nil, reason, err
+	}
+
+	// 2. ログインの記録と異常検知
+	if err := uc.loginMonitor.Evaluate(ctx, user, input.IPAddress, input.UserAgent); err != nil {
+		if errors.Is(err, domain.ErrMFARequired) {
+			return nil, LoginReasonMFARequired, err
+		}
+		return nil, LoginReasonError, err
+	}
+
+	// 3. JWTトークンの生成
+	token, err := uc.jwtService.GeneratePasswordToken(auth.TokenParams{
+		UserID: user.ID,
+		Email:  user.Email,
+		Locale: user.Locale,
+	})
+	if err != nil {
+		return nil, LoginReasonError, err
+	}
+
+	// 4. レスポンスの作成
+	return uc.loginOutput(user, token), LoginReasonNone, nil
+}
+
+// 確認コードによるログインの完了（Login が domain.ErrMFARequired を返した後に呼ぶ）
+// 成功するとそのログインの端末を既知とし、認証方式が MFA のトークンを発行する
+func (uc *UserUseCase) VerifyLogin(ctx context.Context, input VerifyLoginInput) (_ *LoginOutput, err error) {
+	ctx, span := startSpan(ctx, "VerifyLogin")
+	defer func() { finishSpan(span, err) }()
+
+	output, reason, err := uc.verifyLogin(ctx, input)
+	if err != nil {
+		uc.metrics.LoginAttempt(LoginResultFailure, reason)
+		return nil, err
+	}
+	uc.metrics.LoginAttempt(LoginResultSuccess, LoginReasonNone)
+	return output, nil
+}
+
+// ログイン確認の処理（失敗した場合はその理由も返す）
+func (uc *UserUseCase) verifyLogin(ctx context.Context, input VerifyLoginInput) (*LoginOutput, string, error) {
+	// 1. ユーザーの検索とパスワードの検証（確認コードだけではログインできないようにする）
+	user, reason, err := uc.authenticate(ctx, input.Email, input.Password)
+	if err != nil {
+		return nil, reason, err
+	}
+
+	// 2. 確認コードの検証
+	if err := uc.loginMonitor.Verify(ctx, user, input.Code); err != nil {
+		if errors.Is(err, domain.ErrInvalidVerificationCode) {
+			return nil, LoginReasonInvalidCode, err
+		}
+		return nil, LoginReasonError, err
+	}
+
+	// 3. JWTトークンの生成
+	token, err := uc.jwtService.GenerateMFAToken(auth.TokenParams{
+		UserID: user.ID,
+		Email:  user.Email,
+		Locale: user.Locale,
+	})
+	if err != nil {
+		return nil, LoginReasonError, err
+	}
+
+	// 4. レスポンスの作成
+	return uc.loginOutput(user, token), LoginReasonNone, nil
+}
+
+// メールアドレスとパスワードによる認証（失敗した場合はその理由も返す）
+func (uc *UserUseCase) authenticate(ctx context.Context, email, password string) (*domain.User, string, error) {
 	// 1. ユーザーの検索
 	user, err := uc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, LoginReasonError, err
+	}
+	if user == nil {
+		return nil, LoginReasonUserNotFound, domain.ErrInvalidCredentials
+	}
+
+	// 2. パスワードの検証
+	if err := uc.comparePassword(user.Password, password); err != nil {
+		return nil, LoginReasonInvalidPassword, domain.ErrInvalidCredentials
+	}
+	return user, LoginReasonNone, nil
+}
+
+// ログインのレスポンスの作成
+func (uc *UserUseCase) loginOutput(user *domain.User, token string) *LoginOutput {
+	return &LoginOutput{
+		Token: token,
+		User: &UserOutput{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Locale:    user.Locale,
+			CreatedAt: user.CreatedAt,
+		},
+		ExpiresAt: time.Now().Add(uc.jwtService.ExpiresIn()), // トークンの有効期限
+	}
+}
+
+// 再認証の入力データ
+type ReauthenticateInput struct {
+	UserID   string
+	Password string
+	// 現在のセッションのスコープ（新しいトークンに引き継ぐ）
+	Scopes []string
+}
+
+// 再認証（現在のセッションの認証時刻を更新したトークンを発行）
+func (uc *UserUseCase) Reauthenticate(ctx context.Context, input ReauthenticateInput) (_ *LoginOutput, err error) {
+	ctx, span := startSpan(ctx, "Reauthenticate", attribute.String("user.id", input.UserID))
+	defer func() { finishSpan(span, err) }()
+
+	// 1. ユーザーの検索
+	user, err := uc.userRepo.FindByID(ctx, input.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -57,74 +231,252 @@ func (uc *UserUseCase) Login(ctx context.Context, email, password string) (*Logi
 	}
 
 	// 2. パスワードの検証
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	if err := uc.comparePassword(user.Password, input.Password); err != nil {
 		return nil, domain.ErrInvalidCredentials
 	}
 
-	// 3. JWTトークンの生成
-	token, err := uc.jwtService.GenerateToken(user.ID, user.Email)
+	// 3. 認証時刻を更新したトークンの生成
+	token, err := uc.jwtService.GeneratePasswordToken(auth.TokenParams{
+		UserID: user.ID,
+		Email:  user.Email,
+		Scopes: input.Scopes,
+		Locale: user.Locale,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// 4. レスポンスの作成
 	return &LoginOutput{
 		Token: token,
 		User: &UserOutput{
 			ID:        user.ID,
 			Email:     user.Email,
 			Name:      user.Name,
+			Locale:    user.Locale,
 			CreatedAt: user.CreatedAt,
 		},
-		ExpiresAt: time.Now().Add(24 * time.Hour), // トークンの有効期限
+		ExpiresAt: time.Now().Add(uc.jwtService.ExpiresIn()),
 	}, nil
 }
 
-// ユーザー作成のユースケース
-func (uc *UserUseCase) CreateUser(ctx context.Context, input CreateUserInput) (*UserOutput, error) {
-	// 1. パスワードのハッシュ化
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+// パスワード変更（呼び出し元で最近の再認証を確認済みであること）
+func (uc *UserUseCase) ChangePassword(ctx context.Context, userID, newPassword string) (err error) {
+	ctx, span := startSpan(ctx, "ChangePassword", attribute.String("user.id", userID))
+	defer func() { finishSpan(span, err) }()
+
+	// 1. パスワード強度の検証
+	if err := domain.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	// 2. パスワードのハッシュ化（再試行に含めないようトランザクションの外で行う）
+	hashedPassword, err := uc.hashPassword(newPassword)
 	if err != nil {
+		return err
+	}
+
+	// 3. ユーザーの検索と保存
+	return uc.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return domain.ErrUserNotFound
+		}
+
+		user.Password = hashedPassword
+		user.UpdatedAt = time.Now()
+
+		return uc.userRepo.Update(ctx, user)
+	})
+}
+
+// メールアドレス変更（呼び出し元で最近の再認証を確認済みであること）
+func (uc *UserUseCase) ChangeEmail(ctx context.Context, userID, newEmail string) (_ *UserOutput, err error) {
+	ctx, span := startSpan(ctx, "ChangeEmail", attribute.String("user.id", userID))
+	defer func() { finishSpan(span, err) }()
+
+	// 1. メールアドレスの形式の検証
+	if err := domain.ValidateEmail(newEmail); err != nil {
 		return nil, err
 	}
 
-	// 2. ドメインオブジェクトの作成
+	// 2. ユーザーの検索と保存
+	// メールアドレスの重複は一意制約で検出され domain.ErrEmailAlreadyExists が返る
+	var user *domain.User
+	err = uc.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return domain.ErrUserNotFound
+		}
+
+		user.Email = newEmail
+		user.UpdatedAt = time.Now()
+
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return uc.outboxRepo.Add(ctx, domain.UserUpdated{
+			UserID:    user.ID,
+			Version:   user.Version,
+			UpdatedAt: user.UpdatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserOutput{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   user.Version,
+		CreatedAt: user.CreatedAt,
+	}, nil
+}
+
+// ユーザー作成のユースケース
+func (uc *UserUseCase) CreateUser(ctx context.Context, input CreateUserInput) (_ *UserOutput, err error) {
+	ctx, span := startSpan(ctx, "CreateUser")
+	defer func() { finishSpan(span, err) }()
+
+	// 1. ドメインオブジェクトの作成
 	user := &domain.User{
 		Email:     input.Email,
-		Password:  string(hashedPassword),
+		Password:  input.Password,
 		Name:      input.Name,
+		Locale:    input.Locale,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	// 3. ドメインのバリデーション
+	// 2. ドメインのバリデーション（ハッシュ化前の平文パスワードを検証する）
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 
-	// 4. メールアドレスの重複チェック
-	existingUser, err := uc.userRepo.FindByEmail(ctx, input.Email)
-	if err == nil && existingUser != nil {
-		return nil, domain.ErrEmailAlreadyExists
+	// 3. パスワードのハッシュ化
+	hashedPassword, err := uc.hashPassword(input.Password)
+	if err != nil {
+		return nil, err
 	}
+	user.Password = hashedPassword
 
-	// 5. ユーザーの保存
-	if err := uc.userRepo.Create(ctx, user); err != nil {
+	// 4. ユーザーとイベントの保存
+	// メールアドレスの重複は一意制約で検出され domain.ErrEmailAlreadyExists が返る
+	err = uc.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		return uc.outboxRepo.Add(ctx, domain.UserRegistered{
+			UserID:       user.ID,
+			RegisteredAt: user.CreatedAt,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// 6. 出力データの作成
+	// 5. 出力データの作成
 	return &UserOutput{
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   user.Version,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
+// ユーザー一覧で受け付ける並び順・絞り込み条件
+var UserListSpec = query.Spec{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+	SortFields:   []string{domain.UserFieldCreatedAt, domain.UserFieldEmail, domain.UserFieldName},
+	DefaultSort:  query.Sort{Field: domain.UserFieldCreatedAt},
+	Filters: []query.FilterField{
+		{Name: domain.UserFieldEmail, Type: query.TypeString, Operators: []query.Operator{query.OpEq, query.OpContains, query.OpPrefix}},
+		{Name: domain.UserFieldName, Type: query.TypeString, Operators: []query.Operator{query.OpEq, query.OpContains, query.OpPrefix}},
+		{Name: domain.UserFieldCreatedAt, Type: query.TypeTime, Operators: []query.Operator{query.OpLt, query.OpLte, query.OpGt, query.OpGte}},
+	},
+}
+
+// ユーザー一覧の出力データ
+type UserListOutput struct {
+	Users []*UserOutput
+	// 次・前のページがない場合は nil
+	Next *query.Cursor
+	Prev *query.Cursor
+}
+
+// ユーザー一覧の取得
+func (uc *UserUseCase) ListUsers(ctx context.Context, params query.Params) (_ *UserListOutput, err error) {
+	ctx, span := startSpan(ctx, "ListUsers", attribute.Int("query.limit", params.Limit), attribute.String("query.sort", params.Sort.String()))
+	defer func() { finishSpan(span, err) }()
+
+	// 1. 1件多く取得して次のページの有無を判定する
+	users, err := uc.userRepo.List(ctx, userListParams(params))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. ページの作成
+	page := query.NewPage(users, params, func(u *domain.User) (string, string) {
+		return u.SortValue(params.Sort.Field), u.ID
+	})
+
+	// 3. 出力データの作成
+	output := &UserListOutput{
+		Users: make([]*UserOutput, 0, len(page.Items)),
+		Next:  page.Next,
+		Prev:  page.Prev,
+	}
+	for _, user := range page.Items {
+		output.Users = append(output.Users, &UserOutput{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Locale:    user.Locale,
+			Version:   user.Version,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+	return output, nil
+}
+
+// 一覧取得の条件をリポジトリの取得条件に変換する
+func userListParams(params query.Params) domain.UserListParams {
+	listParams := domain.UserListParams{
+		Limit:     params.Limit + 1,
+		SortField: params.Sort.Field,
+		// 前方向に取得する場合は逆順に並べる
+		Desc: params.Sort.Desc != (params.Direction() == query.DirectionPrev),
+	}
+	for _, c := range params.Filters {
+		listParams.Filters = append(listParams.Filters, domain.UserFilter{
+			Field:    c.Field,
+			Operator: domain.UserFilterOperator(c.Operator),
+			Value:    c.Value,
+		})
+	}
+	if params.Cursor != nil {
+		listParams.After = &domain.UserListPosition{Value: params.Cursor.Value, ID: params.Cursor.ID}
+	}
+	return listParams
+}
+
 // ユーザー認証のユースケース
 // ユーザー情報取得
-func (uc *UserUseCase) GetUserByID(ctx context.Context, id string) (*UserOutput, error) {
+func (uc *UserUseCase) GetUserByID(ctx context.Context, id string) (_ *UserOutput, err error) {
+	ctx, span := startSpan(ctx, "GetUserByID", attribute.String("user.id", id))
+	defer func() { finishSpan(span, err) }()
+
 	user, err := uc.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -137,24 +489,83 @@ func (uc *UserUseCase) GetUserByID(ctx context.Context, id string) (*UserOutput,
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   user.Version,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
-// プロフィール更新
-func (uc *UserUseCase) UpdateUserProfile(ctx context.Context, userID string, name string) (*UserOutput, error) {
-	user, err := uc.userRepo.FindByID(ctx, userID)
+// 複数のユーザー情報の取得（要求した順に並べ、存在しないユーザーは含めない）
+func (uc *UserUseCase) GetUsersByIDs(ctx context.Context, ids []string) (_ []*UserOutput, err error) {
+	ctx, span := startSpan(ctx, "GetUsersByIDs", attribute.Int("user.count", len(ids)))
+	defer func() { finishSpan(span, err) }()
+
+	users, err := uc.userRepo.FindByIDs(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, domain.ErrUserNotFound
+	byID := make(map[string]*domain.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
 	}
 
-	user.Name = name
-	user.UpdatedAt = time.Now()
+	outputs := make([]*UserOutput, 0, len(users))
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok {
+			continue
+		}
+		// 重複した ID は 1 件にまとめる
+		delete(byID, id)
+		outputs = append(outputs, &UserOutput{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Locale:    user.Locale,
+			Version:   user.Version,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+	return outputs, nil
+}
+
+// プロフィール更新
+func (uc *UserUseCase) UpdateUserProfile(ctx context.Context, input UpdateProfileInput) (_ *UserOutput, err error) {
+	ctx, span := startSpan(ctx, "UpdateUserProfile", attribute.String("user.id", input.UserID))
+	defer func() { finishSpan(span, err) }()
+
+	var user *domain.User
+	err = uc.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.userRepo.FindByID(ctx, input.UserID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return domain.ErrUserNotFound
+		}
 
-	if err := uc.userRepo.Update(ctx, user); err != nil {
+		// クライアントが読み込んだ後に更新されていないか
+		if len(input.ExpectedVersions) > 0 && !slices.Contains(input.ExpectedVersions, user.Version) {
+			return domain.ErrConcurrentModification
+		}
+
+		user.Name = input.Name
+		if input.Locale != "" {
+			user.Locale = input.Locale
+		}
+		user.UpdatedAt = time.Now()
+
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return uc.outboxRepo.Add(ctx, domain.UserUpdated{
+			UserID:    user.ID,
+			Version:   user.Version,
+			UpdatedAt: user.UpdatedAt,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -162,19 +573,48 @@ func (uc *UserUseCase) UpdateUserProfile(ctx context.Context, userID string, nam
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   user.Version,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
 
-func (uc *UserUseCase) AuthenticateUser(ctx context.Context, email, password string) (*UserOutput, error) {
+// ユーザーの削除（呼び出し元で最近の再認証を確認済みであること）
+func (uc *UserUseCase) DeleteUser(ctx context.Context, userID string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteUser", attribute.String("user.id", userID))
+	defer func() { finishSpan(span, err) }()
+
+	return uc.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return domain.ErrUserNotFound
+		}
+
+		if err := uc.userRepo.Delete(ctx, userID); err != nil {
+			return err
+		}
+		return uc.outboxRepo.Add(ctx, domain.UserDeleted{
+			UserID:    userID,
+			DeletedAt: time.Now(),
+		})
+	})
+}
+
+func (uc *UserUseCase) AuthenticateUser(ctx context.Context, email, password string) (_ *UserOutput, err error) {
+	ctx, span := startSpan(ctx, "AuthenticateUser")
+	defer func() { finishSpan(span, err) }()
+
 	// 1. メールアドレスでユーザーを検索
 	user, err := uc.userRepo.FindByEmail(ctx, email)
-	if err != nil {
+	if err != nil || user == nil {
 		return nil, domain.ErrInvalidCredentials
 	}
 
 	// 2. パスワードの検証
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+	if err := uc.comparePassword(user.Password, password); err != nil {
 		return nil, domain.ErrInvalidCredentials
 	}
 
@@ -183,6 +623,8 @@ func (uc *UserUseCase) AuthenticateUser(ctx context.Context, email, password str
 		ID:        user.ID,
 		Email:     user.Email,
 		Name:      user.Name,
+		Locale:    user.Locale,
+		Version:   user.Version,
 		CreatedAt: user.CreatedAt,
 	}, nil
 }
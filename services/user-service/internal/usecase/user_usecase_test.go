@@ -0,0 +1,608 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/domain"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/i18n"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/auth"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/geo"
+	"github.com/MizukiMachine/ecommerce-microservices/services/user-service/internal/infrastructure/persistence"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testPassword = "Password123"
+
+type testEnv struct {
+	uc         *UserUseCase
+	userRepo   domain.UserRepository
+	outbox     *fakeOutboxRepository
+	loginRepo  *fakeLoginEventRepository
+	challenges *fakeLoginChallengeRepository
+	mailer     *fakeMailer
+	jwtService *auth.JWTService
+}
+
+func newTestEnv(t *testing.T, monitorConfig LoginMonitorConfig) *testEnv {
+	t.Helper()
+
+	env := &testEnv{
+		userRepo:   persistence.NewMemoryUserRepository(),
+		outbox:     &fakeOutboxRepository{},
+		loginRepo:  &fakeLoginEventRepository{},
+		challenges: &fakeLoginChallengeRepository{},
+		mailer:     &fakeMailer{},
+		jwtService: auth.NewJWTService(auth.Config{
+			SecretKey:     "test-secret",
+			Expires:       time.Hour,
+			DefaultScopes: []string{auth.ScopeProfileRead, auth.ScopeProfileWrite},
+		}),
+	}
+	monitor := NewLoginMonitor(env.loginRepo, env.challenges, geo.NewNoopLocator(), env.mailer, monitorConfig)
+	env.uc = NewUserUseCase(env.userRepo, env.outbox, fakeTxManager{}, env.jwtService, monitor)
+	return env
+}
+
+func (env *testEnv) createUser(t *testing.T, email string) *UserOutput {
+	t.Helper()
+	user, err := env.uc.CreateUser(context.Background(), CreateUserInput{
+		Email:    email,
+		Password: testPassword,
+		Name:     "Taro Yamada",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	return user
+}
+
+func TestUserUseCase_CreateUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   CreateUserInput
+		wantErr error
+	}{
+		{
+			name:  "valid user",
+			input: CreateUserInput{Email: "taro@example.com", Password: testPassword, Name: "Taro"},
+		},
+		{
+			name:    "invalid email",
+			input:   CreateUserInput{Email: "taro", Password: testPassword, Name: "Taro"},
+			wantErr: domain.ErrInvalidEmail,
+		},
+		{
+			// ハッシュ化前の平文のパスワードを検証する（ハッシュ値は常に強度の条件を満たすため）
+			name:    "weak password",
+			input:   CreateUserInput{Email: "taro@example.com", Password: "password", Name: "Taro"},
+			wantErr: domain.ErrWeakPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, LoginMonitorConfig{})
+
+			output, err := env.uc.CreateUser(context.Background(), tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if got := env.outbox.eventTypes(); len(got) != 0 {
+					t.Errorf("events = %v, want none", got)
+				}
+				return
+			}
+
+			if output.ID == "" || output.Version != 1 {
+				t.Errorf("output = %+v", output)
+			}
+			stored, err := env.userRepo.FindByID(context.Background(), output.ID)
+			if err != nil || stored == nil {
+				t.Fatalf("FindByID() = %v, %v", stored, err)
+			}
+			if stored.Password == tt.input.Password {
+				t.Error("password was stored in plain text")
+			}
+			if got := env.outbox.eventTypes(); !reflect.DeepEqual(got, []string{domain.EventUserRegistered}) {
+				t.Errorf("events = %v, want [%s]", got, domain.EventUserRegistered)
+			}
+		})
+	}
+
+	t.Run("duplicate email", func(t *testing.T) {
+		env := newTestEnv(t, LoginMonitorConfig{})
+		env.createUser(t, "taro@example.com")
+
+		_, err := env.uc.CreateUser(context.Background(), CreateUserInput{Email: "taro@example.com", Password: testPassword, Name: "Taro"})
+		if !errors.Is(err, domain.ErrEmailAlreadyExists) {
+			t.Errorf("CreateUser() error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+		}
+	})
+}
+
+func TestUserUseCase_Login(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "valid credentials", email: "taro@example.com", password: testPassword},
+		{name: "wrong password", email: "taro@example.com", password: "Wrong12345", wantErr: domain.ErrInvalidCredentials},
+		// 存在しないユーザーでも nil を参照せず認証エラーを返す
+		{name: "unknown email", email: "hanako@example.com", password: testPassword, wantErr: domain.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, LoginMonitorConfig{})
+			user := env.createUser(t, "taro@example.com")
+
+			output, err := env.uc.Login(ctx, LoginInput{Email: tt.email, Password: tt.password, UserAgent: "test-agent"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			claims, err := env.jwtService.ValidateToken(output.Token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.UserID != user.ID || output.User.ID != user.ID {
+				t.Errorf("token subject = %s, output user = %s, want %s", claims.UserID, output.User.ID, user.ID)
+			}
+			if len(env.loginRepo.events) != 1 {
+				t.Errorf("recorded %d login events, want 1", len(env.loginRepo.events))
+			}
+		})
+	}
+
+	t.Run("new device requires MFA when configured", func(t *testing.T) {
+		env := newTestEnv(t, LoginMonitorConfig{RequireMFAOnAnomaly: true})
+		env.createUser(t, "taro@example.com")
+
+		// 最初のログインは履歴がないため通常どおり成功する
+		if _, err := env.uc.Login(ctx, LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: "laptop"}); err != nil {
+			t.Fatalf("first Login() error = %v", err)
+		}
+
+		_, err := env.uc.Login(ctx, LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: "unknown-device"})
+		if !errors.Is(err, domain.ErrMFARequired) {
+			t.Fatalf("Login() error = %v, want %v", err, domain.ErrMFARequired)
+		}
+		// 不審なログインの通知と確認コードを送る
+		if len(env.mailer.sent) != 2 {
+			t.Errorf("sent %d mails, want 2", len(env.mailer.sent))
+		}
+
+		// 確認が済むまでは履歴として信頼しないため、同じ端末からの再試行にもMFAを要求する
+		_, err = env.uc.Login(ctx, LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: "unknown-device"})
+		if !errors.Is(err, domain.ErrMFARequired) {
+			t.Fatalf("retried Login() error = %v, want %v", err, domain.ErrMFARequired)
+		}
+	})
+
+	t.Run("new device only alerts by default", func(t *testing.T) {
+		env := newTestEnv(t, LoginMonitorConfig{})
+		env.createUser(t, "taro@example.com")
+
+		for _, ua := range []string{"laptop", "unknown-device"} {
+			if _, err := env.uc.Login(ctx, LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: ua}); err != nil {
+				t.Fatalf("Login(%s) error = %v", ua, err)
+			}
+		}
+		if len(env.mailer.sent) != 1 {
+			t.Errorf("sent %d alerts, want 1", len(env.mailer.sent))
+		}
+	})
+}
+
+// 送信したメールのうち最後の確認コード
+func (env *testEnv) lastVerificationCode(t *testing.T) string {
+	t.Helper()
+	for i := len(env.mailer.sent) - 1; i >= 0; i-- {
+		if code := verificationCodePattern.FindString(env.mailer.sent[i].Body); code != "" {
+			return code
+		}
+	}
+	t.Fatal("no verification code was sent")
+	return ""
+}
+
+var verificationCodePattern = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+func TestUserUseCase_VerifyLogin(t *testing.T) {
+	ctx := context.Background()
+	laptop := LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: "laptop"}
+	phone := LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: "phone"}
+
+	// 既知の端末でログインした後、新しい端末からのログインで確認コードを要求された状態にする
+	setup := func(t *testing.T) *testEnv {
+		t.Helper()
+		env := newTestEnv(t, LoginMonitorConfig{RequireMFAOnAnomaly: true})
+		env.createUser(t, "taro@example.com")
+		if _, err := env.uc.Login(ctx, laptop); err != nil {
+			t.Fatalf("first Login() error = %v", err)
+		}
+		if _, err := env.uc.Login(ctx, phone); !errors.Is(err, domain.ErrMFARequired) {
+			t.Fatalf("Login() error = %v, want %v", err, domain.ErrMFARequired)
+		}
+		return env
+	}
+
+	t.Run("flagged then verified device is trusted", func(t *testing.T) {
+		env := setup(t)
+		code := env.lastVerificationCode(t)
+
+		output, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: testPassword, Code: code})
+		if err != nil {
+			t.Fatalf("VerifyLogin() error = %v", err)
+		}
+		claims, err := env.jwtService.ValidateToken(output.Token)
+		if err != nil {
+			t.Fatalf("ValidateToken() error = %v", err)
+		}
+		if claims.ACR != auth.ACRMFA || claims.AuthTime == nil {
+			t.Errorf("acr = %q, auth_time = %v, want %q with auth_time", claims.ACR, claims.AuthTime, auth.ACRMFA)
+		}
+
+		// 確認コードは一度しか使えない
+		if _, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: testPassword, Code: code}); !errors.Is(err, domain.ErrInvalidVerificationCode) {
+			t.Errorf("reused VerifyLogin() error = %v, want %v", err, domain.ErrInvalidVerificationCode)
+		}
+
+		// 確認済みの端末からの次のログインは不審と判定しない
+		sent := len(env.mailer.sent)
+		if _, err := env.uc.Login(ctx, phone); err != nil {
+			t.Fatalf("Login() after verification error = %v", err)
+		}
+		if last := env.loginRepo.events[len(env.loginRepo.events)-1]; last.Suspicious() {
+			t.Errorf("login after verification flagged: %v", last.Flags)
+		}
+		if len(env.mailer.sent) != sent {
+			t.Errorf("sent %d mails after verification, want none", len(env.mailer.sent)-sent)
+		}
+	})
+
+	t.Run("password is required", func(t *testing.T) {
+		env := setup(t)
+		code := env.lastVerificationCode(t)
+
+		_, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: "Wrong12345", Code: code})
+		if !errors.Is(err, domain.ErrInvalidCredentials) {
+			t.Errorf("VerifyLogin() error = %v, want %v", err, domain.ErrInvalidCredentials)
+		}
+	})
+
+	t.Run("too many wrong codes", func(t *testing.T) {
+		env := setup(t)
+		code := env.lastVerificationCode(t)
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+
+		for i := 0; i < maxVerificationAttempts; i++ {
+			_, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: testPassword, Code: wrong})
+			if !errors.Is(err, domain.ErrInvalidVerificationCode) {
+				t.Fatalf("VerifyLogin(wrong) error = %v, want %v", err, domain.ErrInvalidVerificationCode)
+			}
+		}
+		// 上限に達した後は正しいコードも受け付けない（ログインし直して新しいコードを受け取る）
+		if _, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: testPassword, Code: code}); !errors.Is(err, domain.ErrInvalidVerificationCode) {
+			t.Errorf("VerifyLogin() after lockout error = %v, want %v", err, domain.ErrInvalidVerificationCode)
+		}
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		env := setup(t)
+		code := env.lastVerificationCode(t)
+		for _, challenge := range env.challenges.challenges {
+			challenge.ExpiresAt = time.Now().Add(-time.Second)
+		}
+
+		if _, err := env.uc.VerifyLogin(ctx, VerifyLoginInput{Email: phone.Email, Password: testPassword, Code: code}); !errors.Is(err, domain.ErrInvalidVerificationCode) {
+			t.Errorf("VerifyLogin() error = %v, want %v", err, domain.ErrInvalidVerificationCode)
+		}
+	})
+}
+
+func TestDeviceID(t *testing.T) {
+	chrome120 := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36"
+	chrome121 := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.6167.85 Safari/537.36"
+	firefox := "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+
+	// ブラウザの更新では同じ端末とみなす
+	if deviceID(chrome120) != deviceID(chrome121) {
+		t.Error("browser update changed the device ID")
+	}
+	if deviceID(chrome121) == deviceID(firefox) {
+		t.Error("different browsers have the same device ID")
+	}
+}
+
+func TestUserUseCase_LoginAlertLocale(t *testing.T) {
+	tests := []struct {
+		name        string
+		userLocale  string
+		ctxLocale   string
+		wantSubject string
+	}{
+		{name: "user preference", userLocale: i18n.Japanese, ctxLocale: i18n.English, wantSubject: "アカウントへの新しいログインがありました"},
+		{name: "request language", ctxLocale: i18n.Japanese, wantSubject: "アカウントへの新しいログインがありました"},
+		{name: "english", userLocale: i18n.English, ctxLocale: i18n.Japanese, wantSubject: "New sign-in to your account"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, LoginMonitorConfig{})
+			if _, err := env.uc.CreateUser(context.Background(), CreateUserInput{
+				Email:    "taro@example.com",
+				Password: testPassword,
+				Name:     "Taro Yamada",
+				Locale:   tt.userLocale,
+			}); err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+
+			ctx := i18n.WithLocale(context.Background(), tt.ctxLocale)
+			for _, ua := range []string{"laptop", "unknown-device"} {
+				if _, err := env.uc.Login(ctx, LoginInput{Email: "taro@example.com", Password: testPassword, UserAgent: ua}); err != nil {
+					t.Fatalf("Login(%s) error = %v", ua, err)
+				}
+			}
+
+			if len(env.mailer.sent) != 1 {
+				t.Fatalf("sent %d alerts, want 1", len(env.mailer.sent))
+			}
+			msg := env.mailer.sent[0]
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Body, "Taro Yamada") || !strings.Contains(msg.Body, "unknown-device") {
+				t.Errorf("body = %q", msg.Body)
+			}
+		})
+	}
+}
+
+func TestUserUseCase_LoginMetrics(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, LoginMonitorConfig{RequireMFAOnAnomaly: true})
+	metrics := &fakeMetrics{}
+	env.uc.SetMetrics(metrics)
+	env.createUser(t, "taro@example.com")
+
+	attempts := []LoginInput{
+		{Email: "taro@example.com", Password: testPassword, UserAgent: "laptop"},
+		{Email: "taro@example.com", Password: "Wrong12345", UserAgent: "laptop"},
+		{Email: "hanako@example.com", Password: testPassword, UserAgent: "laptop"},
+		{Email: "taro@example.com", Password: testPassword, UserAgent: "unknown-device"},
+	}
+	for _, input := range attempts {
+		env.uc.Login(ctx, input)
+	}
+
+	want := []string{
+		"success/none",
+		"failure/invalid_password",
+		"failure/user_not_found",
+		"failure/mfa_required",
+	}
+	if !reflect.DeepEqual(metrics.logins, want) {
+		t.Errorf("login attempts = %v, want %v", metrics.logins, want)
+	}
+	// ハッシュ化はユーザー作成時の1回、照合はユーザーが存在する3回
+	if metrics.bcrypt[BcryptHash] != 1 || metrics.bcrypt[BcryptCompare] != 3 {
+		t.Errorf("bcrypt observations = %v", metrics.bcrypt)
+	}
+}
+
+func TestUserUseCase_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	env := newTestEnv(t, LoginMonitorConfig{})
+	env.createUser(t, "taro@example.com")
+	env.uc.Login(context.Background(), LoginInput{Email: "taro@example.com", Password: "Wrong12345"})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "UserUseCase.CreateUser" || spans[1].Name() != "UserUseCase.Login" {
+		t.Fatalf("spans = %v", spans)
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("CreateUser span status = %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != domain.ErrInvalidCredentials.Error() {
+		t.Errorf("Login span status = %v", spans[1].Status())
+	}
+}
+
+func TestUserUseCase_GetUsersByIDs(t *testing.T) {
+	env := newTestEnv(t, LoginMonitorConfig{})
+	taro := env.createUser(t, "taro@example.com")
+	hanako := env.createUser(t, "hanako@example.com")
+
+	// 要求した順に並び、存在しない ID と重複は除かれる
+	users, err := env.uc.GetUsersByIDs(context.Background(), []string{hanako.ID, "missing", taro.ID, hanako.ID})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs() error = %v", err)
+	}
+	var got []string
+	for _, u := range users {
+		got = append(got, u.Email)
+	}
+	if want := []string{"hanako@example.com", "taro@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("emails = %v, want %v", got, want)
+	}
+}
+
+func TestUserUseCase_UpdateUserProfile(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name             string
+		userID           func(user *UserOutput) string
+		expectedVersions []int
+		wantErr          error
+	}{
+		{name: "without version check", userID: func(u *UserOutput) string { return u.ID }},
+		{name: "matching version", userID: func(u *UserOutput) string { return u.ID }, expectedVersions: []int{1}},
+		{name: "one of several versions", userID: func(u *UserOutput) string { return u.ID }, expectedVersions: []int{3, 1}},
+		{name: "stale version", userID: func(u *UserOutput) string { return u.ID }, expectedVersions: []int{2}, wantErr: domain.ErrConcurrentModification},
+		{name: "missing user", userID: func(*UserOutput) string { return "missing" }, wantErr: domain.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, LoginMonitorConfig{})
+			user := env.createUser(t, "taro@example.com")
+
+			output, err := env.uc.UpdateUserProfile(ctx, UpdateProfileInput{
+				UserID:           tt.userID(user),
+				Name:             "Hanako Yamada",
+				ExpectedVersions: tt.expectedVersions,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUserProfile() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if output.Name != "Hanako Yamada" || output.Version != 2 {
+				t.Errorf("output = %+v", output)
+			}
+			want := []string{domain.EventUserRegistered, domain.EventUserUpdated}
+			if got := env.outbox.eventTypes(); !reflect.DeepEqual(got, want) {
+				t.Errorf("events = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUserUseCase_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, LoginMonitorConfig{})
+	user := env.createUser(t, "taro@example.com")
+
+	if err := env.uc.ChangePassword(ctx, user.ID, "weak"); !errors.Is(err, domain.ErrWeakPassword) {
+		t.Errorf("ChangePassword() with weak password error = %v, want %v", err, domain.ErrWeakPassword)
+	}
+	if err := env.uc.ChangePassword(ctx, "missing", "NewPassword456"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("ChangePassword() for missing user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	if err := env.uc.ChangePassword(ctx, user.ID, "NewPassword456"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := env.uc.AuthenticateUser(ctx, "taro@example.com", testPassword); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("old password still accepted: %v", err)
+	}
+	if _, err := env.uc.AuthenticateUser(ctx, "taro@example.com", "NewPassword456"); err != nil {
+		t.Errorf("new password rejected: %v", err)
+	}
+}
+
+func TestUserUseCase_ChangeEmail(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, LoginMonitorConfig{})
+	user := env.createUser(t, "taro@example.com")
+	env.createUser(t, "hanako@example.com")
+
+	if _, err := env.uc.ChangeEmail(ctx, user.ID, "not-an-email"); !errors.Is(err, domain.ErrInvalidEmail) {
+		t.Errorf("ChangeEmail() with invalid email error = %v, want %v", err, domain.ErrInvalidEmail)
+	}
+	if _, err := env.uc.ChangeEmail(ctx, user.ID, "hanako@example.com"); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Errorf("ChangeEmail() to a taken email error = %v, want %v", err, domain.ErrEmailAlreadyExists)
+	}
+	if _, err := env.uc.ChangeEmail(ctx, "missing", "jiro@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("ChangeEmail() for missing user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	output, err := env.uc.ChangeEmail(ctx, user.ID, "taro.yamada@example.com")
+	if err != nil {
+		t.Fatalf("ChangeEmail() error = %v", err)
+	}
+	if output.Email != "taro.yamada@example.com" || output.Version != 2 {
+		t.Errorf("output = %+v", output)
+	}
+	// 新しいメールアドレスでログインできる
+	if _, err := env.uc.AuthenticateUser(ctx, "taro.yamada@example.com", testPassword); err != nil {
+		t.Errorf("new email rejected: %v", err)
+	}
+	if _, err := env.uc.AuthenticateUser(ctx, "taro@example.com", testPassword); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("old email still accepted: %v", err)
+	}
+
+	want := []string{domain.EventUserRegistered, domain.EventUserRegistered, domain.EventUserUpdated}
+	if got := env.outbox.eventTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestUserUseCase_Reauthenticate(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, LoginMonitorConfig{})
+	user := env.createUser(t, "taro@example.com")
+
+	if _, err := env.uc.Reauthenticate(ctx, ReauthenticateInput{UserID: user.ID, Password: "Wrong12345"}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Reauthenticate() with wrong password error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+
+	output, err := env.uc.Reauthenticate(ctx, ReauthenticateInput{
+		UserID:   user.ID,
+		Password: testPassword,
+		Scopes:   []string{auth.ScopeProfileRead},
+	})
+	if err != nil {
+		t.Fatalf("Reauthenticate() error = %v", err)
+	}
+	claims, err := env.jwtService.ValidateToken(output.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(claims.Scopes(), []string{auth.ScopeProfileRead}) {
+		t.Errorf("scopes = %v, want [%s]", claims.Scopes(), auth.ScopeProfileRead)
+	}
+	if !claims.AuthenticatedWithin(time.Minute) {
+		t.Error("reauthenticated token does not carry a recent auth_time")
+	}
+}
+
+func TestUserUseCase_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, LoginMonitorConfig{})
+	user := env.createUser(t, "taro@example.com")
+
+	if err := env.uc.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := env.uc.GetUserByID(ctx, user.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("GetUserByID() after delete error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	if err := env.uc.DeleteUser(ctx, user.ID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("second DeleteUser() error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	want := []string{domain.EventUserRegistered, domain.EventUserDeleted}
+	if got := env.outbox.eventTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}